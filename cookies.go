@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadCookieFile reads a Netscape-format cookie file (as exported by browsers and
+// curl) into a cookie jar
+func loadCookieFile(path string) (http.CookieJar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		// HttpOnly cookies are written with a "#HttpOnly_" prefix; other # lines are comments
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line = rest
+			httpOnly = true
+		} else if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: expected 7 tab-separated fields, got %d", path, lineNumber, len(fields))
+		}
+
+		domain, includeSubdomains, cookiePath, secure, expiry, name, value :=
+			fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+
+		cookie := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     cookiePath,
+			Secure:   strings.EqualFold(secure, "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(includeSubdomains, "TRUE") {
+			cookie.Domain = domain
+		}
+		if seconds, err := strconv.ParseInt(expiry, 10, 64); err == nil && seconds > 0 {
+			cookie.Expires = time.Unix(seconds, 0)
+		}
+
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: cookiePath}, []*http.Cookie{cookie})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return jar, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import "github.com/bariiss/hls-proxy-warm/pkg/hlswarm"
+
+// Process exit codes, so scripts can tell why a run failed. When several apply,
+// the lowest non-zero code wins. Usage and configuration errors exit with 1.
+const (
+	exitOK            = 0
+	exitParseFailed   = 2
+	exitSegmentErrors = 3
+	exitLowHitRatio   = 4
+)
+
+// exitStatus accumulates the outcome of every warmed stream into an exit code
+type exitStatus struct {
+	minHitRatio   float64
+	parseFailed   bool
+	segmentErrors bool
+	lowHitRatio   bool
+}
+
+// record notes the outcome of one stream: err is a playlist failure, result the
+// warm result, which is nil when there was nothing to warm. Segments answered
+// with an HTTP error status count as errored just like failed requests.
+func (s *exitStatus) record(result *hlswarm.WarmResult, err error) {
+	if err != nil {
+		s.parseFailed = true
+		return
+	}
+	if result == nil || result.TotalFiles == 0 {
+		return
+	}
+	for _, detail := range result.Details {
+		if detail.Error != nil || detail.StatusCode >= 400 {
+			s.segmentErrors = true
+		}
+	}
+	if s.minHitRatio > 0 && result.SuccessRatio() < s.minHitRatio {
+		s.lowHitRatio = true
+	}
+}
+
+// code returns the process exit code for the recorded outcomes
+func (s *exitStatus) code() int {
+	switch {
+	case s.parseFailed:
+		return exitParseFailed
+	case s.segmentErrors:
+		return exitSegmentErrors
+	case s.lowHitRatio:
+		return exitLowHitRatio
+	default:
+		return exitOK
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bariiss/hls-proxy-warm/pkg/hlswarm"
+)
+
+// headerFlag collects repeated -header "Key: Value" flags
+type headerFlag map[string]string
+
+func (f headerFlag) String() string {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+": "+f[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (f headerFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("malformed header %q, expected \"Key: Value\"", value)
+	}
+	f[key] = strings.TrimSpace(val)
+	return nil
+}
+
+// cookieFlag collects repeated -cookie "name=value" flags
+type cookieFlag []*http.Cookie
+
+func (f *cookieFlag) String() string {
+	pairs := make([]string, 0, len(*f))
+	for _, cookie := range *f {
+		pairs = append(pairs, cookie.Name+"="+cookie.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+func (f *cookieFlag) Set(value string) error {
+	cookies, err := http.ParseCookie(value)
+	if err != nil {
+		return fmt.Errorf("malformed cookie %q, expected \"name=value\": %v", value, err)
+	}
+	*f = append(*f, cookies...)
+	return nil
+}
+
+// edgeIPFlag collects repeated -edge-ip flags
+type edgeIPFlag []string
+
+func (f *edgeIPFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *edgeIPFlag) Set(value string) error {
+	ip := net.ParseIP(strings.TrimSpace(value))
+	if ip == nil {
+		return fmt.Errorf("invalid edge IP %q", value)
+	}
+	*f = append(*f, ip.String())
+	return nil
+}
+
+// mirrorFlag collects repeated -mirror flags
+type mirrorFlag []hlswarm.Mirror
+
+func (f *mirrorFlag) String() string {
+	mirrors := make([]string, len(*f))
+	for i, m := range *f {
+		mirrors[i] = fmt.Sprintf("%s=%d", m.Host, m.Weight)
+	}
+	return strings.Join(mirrors, ", ")
+}
+
+func (f *mirrorFlag) Set(value string) error {
+	mirror, err := hlswarm.ParseMirror(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, mirror)
+	return nil
+}
+
+// hostFlag collects repeated -allow-host and -deny-host patterns
+type hostFlag []string
+
+func (f *hostFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *hostFlag) Set(value string) error {
+	pattern := strings.ToLower(strings.TrimSpace(value))
+	host := strings.TrimPrefix(pattern, "*.")
+	if host == "" || strings.ContainsAny(host, "/:*") {
+		return fmt.Errorf("invalid host %q: use a host name or *.domain", value)
+	}
+	*f = append(*f, pattern)
+	return nil
+}
+
+// userAgentFlag collects repeated -user-agent flags
+type userAgentFlag []string
+
+func (f *userAgentFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *userAgentFlag) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("empty User-Agent")
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// redactHeaderFlag collects repeated -redact-header names
+type redactHeaderFlag []string
+
+func (f *redactHeaderFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *redactHeaderFlag) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, ": ") {
+		return fmt.Errorf("invalid header name %q", value)
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// parseLogLevel parses a -log-level value: debug, info, warn or error
+func parseLogLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: must be debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// parseProxyURL validates a -proxy value; http, https and socks5 proxies are supported
+func parseProxyURL(value string) (*url.URL, error) {
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", value)
+	}
+
+	return proxyURL, nil
+}
+
+// parseBasicAuth parses a -basic-auth "user:password" value
+func parseBasicAuth(value string) (*url.Userinfo, error) {
+	user, password, ok := strings.Cut(value, ":")
+	if !ok || user == "" {
+		return nil, errors.New(`expected "user:password"`)
+	}
+	return url.UserPassword(user, password), nil
+}
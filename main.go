@@ -1,100 +1,779 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"flag"
 	"fmt"
 	"log"
+	"maps"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
+
+	"github.com/bariiss/hls-proxy-warm/pkg/hlswarm"
 )
 
 func main() {
 	// Parse command line flags
 	var (
-		referer    = flag.String("referer", "", "Referer header to send with requests")
-		origin     = flag.String("origin", "", "Origin header to send with requests")
-		playbackID = flag.String("playback-id", "", "X-Playback-Session-Id header (auto-generated if not provided)")
-		workers    = flag.Int("workers", defaultWorkers, "Number of parallel workers")
-		daemon     = flag.Bool("daemon", false, "Run in daemon mode (continuously)")
-		interval   = flag.Duration("interval", defaultInterval, "Check interval for daemon mode")
-		rewarmLast = flag.Int("rewarm-last", 0, "Rewarm last N segments every cycle")
-		ttl        = flag.Duration("ttl", defaultTTL, "How long before a processed segment is considered stale")
-		debug      = flag.Bool("debug", false, "Show debug information including headers")
-		quiet      = flag.Bool("quiet", false, "Suppress detailed output (only show summary)")
-		help       = flag.Bool("help", false, "Show help message")
+		referer           = flag.String("referer", "", "Referer header to send with requests")
+		origin            = flag.String("origin", "", "Origin header to send with requests")
+		playbackID        = flag.String("playback-id", "", "X-Playback-Session-Id header (auto-generated if not provided)")
+		minimalHeaders    = flag.Bool("minimal-headers", false, "Send only Accept and explicitly configured headers instead of emulating a browser (default: browser headers)")
+		workers           = flag.Int("workers", hlswarm.DefaultWorkers, "Number of parallel workers")
+		perHostWorkers    = flag.Int("per-host-workers", 0, "Maximum concurrent segment requests per host across all streams (0 = unlimited)")
+		adaptiveWorkers   = flag.Bool("adaptive-workers", false, "Resize each stream's workers every daemon cycle based on p95 latency and error rate")
+		minWorkers        = flag.Int("min-workers", 1, "Fewest workers -adaptive-workers scales a stream down to")
+		maxWorkers        = flag.Int("max-workers", 0, "Most workers -adaptive-workers scales a stream up to (0 = 4 times -workers)")
+		streamConcurrency = flag.Int("stream-concurrency", 1, "Number of playlists warmed in parallel in one-shot mode, each with its own workers")
+		method            = flag.String("method", http.MethodGet, "HTTP method used to warm segments: GET or HEAD")
+		cacheHeader       = flag.String("cache-header", "", "Response header that decides cache hits, replacing the built-in heuristics")
+		cacheHitValue     = flag.String("cache-hit-value", hlswarm.DefaultCacheHitValue, "Case-insensitive substring of -cache-header that means a hit")
+		noAgeFallback     = flag.Bool("no-age-fallback", false, "Do not count a non-zero Age header as a cache hit")
+		rateLimit         = flag.Float64("rate", 0, "Maximum requests per second across all workers (0 = unlimited)")
+		pace              = flag.Bool("pace", false, "In daemon mode, spread each cycle's requests evenly over the polling interval instead of bursting")
+		daemon            = flag.Bool("daemon", false, "Run in daemon mode (continuously)")
+		minHitRatio       = flag.Float64("min-hit-ratio", 0, "Exit with code 4 if a stream's success ratio (0-1) under -success-criteria is below this")
+		successCriteria   = flag.String("success-criteria", hlswarm.SuccessCacheHit, "What counts as a successful segment: cache-hit, status-2xx or both")
+		onceThenExit      = flag.Bool("once-then-exit", false, "Run a single daemon cycle per stream, warming only new segments, then exit")
+		interval          = flag.Duration("interval", 0, "Check interval for daemon mode (default: half of EXT-X-TARGETDURATION, else 1s)")
+		metricsAddr       = flag.String("metrics-addr", "", "Address to serve Prometheus metrics on in daemon mode (e.g. :9090)")
+		otlpEndpoint      = flag.String("otlp-endpoint", "", "OTLP/HTTP endpoint to export trace spans to (e.g. http://localhost:4318)")
+		liveEdge          = flag.Bool("live-edge", false, "In daemon mode, warm only the newest segment of each live playlist, polling again when its EXTINF duration has passed")
+		minInterval       = flag.Duration("min-interval", 0, "With -max-interval, the shortest daemon polling interval while new segments keep appearing (default: -interval)")
+		maxInterval       = flag.Duration("max-interval", 0, "In daemon mode, back off polling toward this interval while a live stream finds no new segments (0 = fixed interval)")
+		jitter            = flag.Float64("jitter", 0, "Randomize each daemon polling interval by up to ±this fraction (0-1), staggering streams")
+		predictAhead      = flag.Int("predict-ahead", 0, "In daemon mode, speculatively warm N numbered segments past the live edge")
+		edgeFirstN        = flag.Int("edge-first", 0, "Warm the newest N segments of each playlist (the live edge) first")
+		segmentsLimit     = flag.Int("segments-limit", 0, "Warm at most the newest N media segments per run or daemon cycle, leaving the rest for later cycles (0 = unlimited)")
+		variant           = flag.String("variant", hlswarm.VariantAll, "Master playlist variants to warm: all, lowest, highest or a bandwidth in bits/s")
+		mediaTypesFlag    = flag.String("media-types", "", "Comma-separated EXT-X-MEDIA rendition types to warm: audio, video, subtitles, closed-captions (default all)")
+		rewarmLast        = flag.Int("rewarm-last", 0, "Rewarm last N segments every cycle")
+		ttl               = flag.Duration("ttl", hlswarm.DefaultTTL, "How long before a processed segment is considered stale")
+		maxRetries        = flag.Int("max-retries", hlswarm.DefaultMaxRetries, "Retries for segments failing with network errors, 5xx or 429")
+		retryDelay        = flag.Duration("retry-base-delay", hlswarm.DefaultRetryBaseDelay, "Initial retry delay, doubled on each attempt")
+		warmUntilHit      = flag.Int("warm-until-hit", 0, "Re-request missed segments up to N times until they are cache hits")
+		untilHitDelay     = flag.Duration("warm-until-hit-delay", hlswarm.DefaultWarmUntilHitDelay, "Delay between re-requests in -warm-until-hit mode")
+		maxRetryAfter     = flag.Duration("max-retry-after", hlswarm.DefaultMaxRetryAfter, "Maximum delay honored from a Retry-After header")
+		breakerFailures   = flag.Int("breaker-failures", 0, "In daemon mode, back off a stream after N consecutive failed cycles (0 = disabled)")
+		breakerErrorRate  = flag.Float64("breaker-error-rate", hlswarm.DefaultBreakerErrorRate, "Share of errored segments (0-1) that fails a cycle for -breaker-failures and -webhook-url")
+		breakerMaxBackoff = flag.Duration("breaker-max-backoff", hlswarm.DefaultBreakerMaxBackoff, "Longest pause of a stream whose circuit is open")
+		webhookURL        = flag.String("webhook-url", "", "In daemon mode, POST a JSON notification to this URL when a stream's cycle fails")
+		webhookHitRatio   = flag.Float64("webhook-min-hit-ratio", 0, "Also notify -webhook-url when a cycle's hit ratio (0-1) is below this (0 = failures only)")
+		webhookInterval   = flag.Duration("webhook-interval", hlswarm.DefaultWebhookInterval, "Shortest time between two -webhook-url notifications for the same stream")
+		checkContinuity   = flag.Bool("check-continuity", false, "Report playlist segments that 404 and, in daemon mode, media sequence gaps between cycles")
+		strict            = flag.Bool("strict", false, "Fail on malformed playlists instead of warning")
+		staleCycles       = flag.Int("stale-cycles", 0, "In daemon mode, warn when a live stream finds no new segments for N consecutive cycles (0 = disabled)")
+		connectTimeout    = flag.Duration("connect-timeout", hlswarm.DefaultConnectTimeout, "Timeout for establishing a connection, including the TLS handshake")
+		requestTimeout    = flag.Duration("request-timeout", hlswarm.DefaultRequestTimeout, "Timeout for a whole request, including reading the body")
+		maxBodyBytes      = flag.Int64("max-body-bytes", 0, "Abort segment downloads larger than this many bytes (0 = unlimited)")
+		verify            = flag.Bool("verify", false, "After warming, wait -verify-delay and re-request every segment to check it is still cached")
+		verifyDelay       = flag.Duration("verify-delay", hlswarm.DefaultVerifyDelay, "Delay before the -verify pass")
+		purge             = flag.Bool("purge", false, "Before warming, send a purge request for every segment so the warm fetches fresh content")
+		purgeMethod       = flag.String("purge-method", hlswarm.DefaultPurgeMethod, "HTTP method of -purge requests")
+		compareMethods    = flag.Int("compare-methods", 0, "Before warming, request this many sampled segments with HEAD, a ranged GET and GET and compare their cache status (0 disables)")
+		maxDuration       = flag.Duration("max-duration", 0, "Stop a one-shot warm after this long and report the partial result (0 = unlimited)")
+		acceptEncoding    = flag.String("accept-encoding", "", "Accept-Encoding for segment requests, e.g. identity, gzip or br (default: gzip)")
+		prefetchBytes     = flag.Int64("prefetch-bytes", 0, "Only request the first N bytes of each segment with a Range header (0 = whole segments)")
+		maxRedirects      = flag.Int("max-redirects", hlswarm.DefaultMaxRedirects, "Maximum redirects followed per request before it fails")
+		warmFrom          = flag.Duration("warm-from", 0, "Only warm media segments from this playback time on, e.g. 5m")
+		warmTo            = flag.Duration("warm-to", 0, "Only warm media segments before this playback time (0 = until the end)")
+		checkContentType  = flag.Bool("check-content-type", false, "Flag segment responses whose Content-Type is not a media type as suspicious")
+		minSegmentBytes   = flag.Int64("min-segment-bytes", 0, "Flag media segment responses smaller than this many bytes as suspicious (0 = disabled)")
+		debug             = flag.Bool("debug", false, "Show debug information including headers")
+		quiet             = flag.Bool("quiet", false, "Suppress detailed output (only show summary)")
+		logLevel          = flag.String("log-level", "info", "Minimum log level: debug, info, warn or error")
+		logFormat         = flag.String("log-format", hlswarm.LogFormatText, "Log format: text or json")
+		output            = flag.String("output", hlswarm.OutputText, "Result formats, comma-separated: text, json and csv")
+		reportFile        = flag.String("report-file", "", "File to append CSV rows to with -output csv (default stdout)")
+		apiAddr           = flag.String("api-addr", "", "Address to serve the HTTP control API on in daemon mode (e.g. :8080)")
+		dedupIgnore       = flag.String("dedup-ignore-query", "", "Comma-separated query parameters to ignore when detecting new segments (\"*\" = all)")
+		maxTracked        = flag.Int("max-tracked-segments", 0, "Maximum processed segments remembered in daemon mode (0 = only expire after -ttl)")
+		stateFile         = flag.String("state-file", "", "File to persist processed segments to across daemon restarts")
+		pruneState        = flag.Bool("prune-state", false, "Remove -state-file entries older than -ttl, report how many were pruned and exit without warming")
+		configPath        = flag.String("config", "", "Path to a YAML or JSON file describing streams and their settings")
+		help              = flag.Bool("help", false, "Show help message")
 	)
 
+	headers := make(headerFlag)
+	flag.Var(headers, "header", "Extra request header as \"Key: Value\" (repeatable)")
+	purgeHeaders := make(headerFlag)
+	flag.Var(purgeHeaders, "purge-header", "Header sent with -purge requests only, e.g. an API token, as \"Key: Value\" (repeatable)")
+	var cookies cookieFlag
+	flag.Var(&cookies, "cookie", "Cookie sent with every request as \"name=value\" (repeatable)")
+	cookieFile := flag.String("cookie-file", "", "Netscape-format cookie jar file to load")
+	var redactHeaders redactHeaderFlag
+	flag.Var(&redactHeaders, "redact-header", "Header whose value is hidden in debug logs, besides Authorization, Cookie and other credentials (repeatable)")
+	segmentKeywords := flag.String("segment-keywords", strings.Join(hlswarm.DefaultSegmentKeywords, ","), "Comma-separated keywords marking playlist lines without a \".\" as segments")
+	noSegmentFilter := flag.Bool("no-segment-filter", false, "Treat every non-comment playlist line as a segment URL")
+	baseURL := flag.String("base-url", "", "URL that relative references in local playlist files resolve against")
+	basicAuth := flag.String("basic-auth", "", "HTTP Basic credentials as \"user:password\", sent to each playlist's host")
+	var userAgents userAgentFlag
+	flag.Var(&userAgents, "user-agent", "User-Agent to send, rotated round-robin across requests when repeated")
+	userAgentFile := flag.String("user-agent-file", "", "File of User-Agents to rotate through, one per line")
+	var allowHosts, denyHosts hostFlag
+	flag.Var(&allowHosts, "allow-host", "Only fetch segments and variant playlists from this host or *.domain (repeatable)")
+	flag.Var(&denyHosts, "deny-host", "Never fetch segments or variant playlists from this host or *.domain (repeatable)")
+	var mirrors mirrorFlag
+	flag.Var(&mirrors, "mirror", "Origin host mirroring the content as \"host[:port][=weight]\"; segment requests are spread across mirrors by weight (repeatable)")
+	var edgeIPs edgeIPFlag
+	flag.Var(&edgeIPs, "edge-ip", "Edge IP to warm every segment against, keeping the URL's Host and TLS name (repeatable)")
+	insecureSkipVerify := flag.Bool("insecure-skip-verify", false, "Do not verify TLS certificates (testing only)")
+	caFile := flag.String("ca-file", "", "PEM bundle of CA certificates to verify TLS connections against")
+	clientCert := flag.String("client-cert", "", "PEM client certificate for mutual TLS (requires -client-key)")
+	clientKey := flag.String("client-key", "", "PEM private key for -client-cert")
+	http1Only := flag.Bool("http1-only", false, "Disable HTTP/2 and warm over HTTP/1.1 only")
+	ipVersion := flag.String("ip-version", "", "Address family to connect over: 4, 6, or both to warm every segment over each")
+	urlFile := flag.String("url-file", "", "File with one playlist URL per line (# comments allowed); - reads stdin")
+	proxy := flag.String("proxy", "", "Proxy URL (http://, https:// or socks5://); defaults to HTTP_PROXY/HTTPS_PROXY")
+
 	flag.Parse()
 
-	if *help || flag.NArg() < 1 {
+	if *help || (flag.NArg() < 1 && *configPath == "" && *apiAddr == "" && *urlFile == "" && !*pruneState) {
 		printHelp()
 		os.Exit(0)
 	}
 
+	if *method != http.MethodGet && *method != http.MethodHead {
+		log.Fatalf("⚠️ Invalid -method %q: must be GET or HEAD", *method)
+	}
+
+	outputs := splitList(*output)
+	for _, format := range outputs {
+		if format != hlswarm.OutputText && format != hlswarm.OutputJSON && format != hlswarm.OutputCSV {
+			log.Fatalf("⚠️ Invalid -output %q: must be %s, %s or %s, or a comma-separated list", format, hlswarm.OutputText, hlswarm.OutputJSON, hlswarm.OutputCSV)
+		}
+	}
+	if *reportFile != "" && !slices.Contains(outputs, hlswarm.OutputCSV) {
+		log.Fatalf("⚠️ -report-file requires -output %s", hlswarm.OutputCSV)
+	}
+
+	level, err := parseLogLevel(*logLevel)
+	if err != nil {
+		log.Fatalf("⚠️ Invalid -log-level: %v", err)
+	}
+
+	if *logFormat != hlswarm.LogFormatText && *logFormat != hlswarm.LogFormatJSON {
+		log.Fatalf("⚠️ Invalid -log-format %q: must be %s or %s", *logFormat, hlswarm.LogFormatText, hlswarm.LogFormatJSON)
+	}
+
+	if *minHitRatio < 0 || *minHitRatio > 1 {
+		log.Fatalf("⚠️ Invalid -min-hit-ratio %v: must be between 0 and 1", *minHitRatio)
+	}
+
+	if *liveEdge {
+		switch {
+		case !*daemon:
+			log.Fatalf("⚠️ -live-edge requires -daemon")
+		case *maxInterval > 0:
+			log.Fatalf("⚠️ -live-edge cannot be combined with -max-interval, it times polls by segment durations")
+		case *rewarmLast > 0:
+			log.Fatalf("⚠️ -live-edge cannot be combined with -rewarm-last, it skips segments behind the live edge")
+		case *pace:
+			log.Fatalf("⚠️ -live-edge cannot be combined with -pace")
+		}
+	}
+	if *minInterval < 0 || *maxInterval < 0 {
+		log.Fatalf("⚠️ Invalid -min-interval/-max-interval: must not be negative")
+	}
+	if *minInterval > 0 && *maxInterval == 0 {
+		log.Fatalf("⚠️ -min-interval requires -max-interval")
+	}
+	if *minInterval > *maxInterval {
+		log.Fatalf("⚠️ Invalid -min-interval %v: must not exceed -max-interval %v", *minInterval, *maxInterval)
+	}
+	if *maxInterval > 0 && !*daemon {
+		log.Fatalf("⚠️ -max-interval requires -daemon")
+	}
+
+	if *jitter < 0 || *jitter >= 1 {
+		log.Fatalf("⚠️ Invalid -jitter %v: must be at least 0 and below 1", *jitter)
+	}
+
+	if *perHostWorkers < 0 {
+		log.Fatalf("⚠️ Invalid -per-host-workers %d: must not be negative", *perHostWorkers)
+	}
+
+	if *minWorkers < 1 {
+		log.Fatalf("⚠️ Invalid -min-workers %d: must be at least 1", *minWorkers)
+	}
+	if *maxWorkers < 0 {
+		log.Fatalf("⚠️ Invalid -max-workers %d: must not be negative", *maxWorkers)
+	}
+
+	if *verify && (*daemon || *onceThenExit) {
+		log.Fatalf("⚠️ -verify is only supported for one-shot runs, not -daemon or -once-then-exit")
+	}
+	if *verifyDelay <= 0 {
+		log.Fatalf("⚠️ Invalid -verify-delay %v: must be positive", *verifyDelay)
+	}
+	if *purge && (*daemon || *onceThenExit) {
+		log.Fatalf("⚠️ -purge is only supported for one-shot runs, not -daemon or -once-then-exit")
+	}
+	if !*purge && len(purgeHeaders) > 0 {
+		log.Fatalf("⚠️ -purge-header requires -purge")
+	}
+	if *purgeMethod == "" || strings.ContainsAny(*purgeMethod, " \t\r\n") {
+		log.Fatalf("⚠️ Invalid -purge-method %q", *purgeMethod)
+	}
+	if *compareMethods < 0 {
+		log.Fatalf("⚠️ Invalid -compare-methods %d: must not be negative", *compareMethods)
+	}
+	if *compareMethods > 0 && (*daemon || *onceThenExit) {
+		log.Fatalf("⚠️ -compare-methods is only supported for one-shot runs, not -daemon or -once-then-exit")
+	}
+
+	if *maxDuration < 0 {
+		log.Fatalf("⚠️ Invalid -max-duration %v: must not be negative", *maxDuration)
+	}
+	if *maxDuration > 0 && (*daemon || *onceThenExit) {
+		log.Fatalf("⚠️ -max-duration is only supported for one-shot runs, not -daemon or -once-then-exit")
+	}
+
+	if *breakerFailures < 0 {
+		log.Fatalf("⚠️ Invalid -breaker-failures %d: must not be negative", *breakerFailures)
+	}
+	if *breakerErrorRate <= 0 || *breakerErrorRate > 1 {
+		log.Fatalf("⚠️ Invalid -breaker-error-rate %v: must be above 0 and at most 1", *breakerErrorRate)
+	}
+	if *breakerMaxBackoff <= 0 {
+		log.Fatalf("⚠️ Invalid -breaker-max-backoff %v: must be positive", *breakerMaxBackoff)
+	}
+
+	if *webhookURL != "" {
+		parsedURL, err := url.Parse(*webhookURL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			log.Fatalf("⚠️ Invalid -webhook-url %q: must be an http or https URL", *webhookURL)
+		}
+		if !*daemon {
+			log.Fatalf("⚠️ -webhook-url requires -daemon")
+		}
+	}
+	if *webhookHitRatio < 0 || *webhookHitRatio > 1 {
+		log.Fatalf("⚠️ Invalid -webhook-min-hit-ratio %v: must be between 0 and 1", *webhookHitRatio)
+	}
+	if *webhookHitRatio > 0 && *webhookURL == "" {
+		log.Fatalf("⚠️ -webhook-min-hit-ratio requires -webhook-url")
+	}
+	if *webhookInterval < 0 {
+		log.Fatalf("⚠️ Invalid -webhook-interval %v: must not be negative", *webhookInterval)
+	}
+
+	if *pace && !*daemon {
+		log.Fatalf("⚠️ -pace requires -daemon")
+	}
+
+	if *pruneState && *stateFile == "" {
+		log.Fatalf("⚠️ -prune-state requires -state-file")
+	}
+	if *pruneState && (*daemon || *onceThenExit || flag.NArg() > 0 || *urlFile != "") {
+		log.Fatalf("⚠️ -prune-state only maintains -state-file; it cannot be combined with playlist URLs, -daemon or -once-then-exit")
+	}
+
+	if *staleCycles < 0 {
+		log.Fatalf("⚠️ Invalid -stale-cycles %d: must not be negative", *staleCycles)
+	}
+
+	if *segmentsLimit < 0 {
+		log.Fatalf("⚠️ Invalid -segments-limit %d: must not be negative", *segmentsLimit)
+	}
+
+	if *prefetchBytes < 0 {
+		log.Fatalf("⚠️ Invalid -prefetch-bytes %d: must not be negative", *prefetchBytes)
+	}
+
+	if *maxRedirects < 1 {
+		log.Fatalf("⚠️ Invalid -max-redirects %d: must be at least 1", *maxRedirects)
+	}
+
+	if *streamConcurrency < 1 {
+		log.Fatalf("⚠️ Invalid -stream-concurrency %d: must be at least 1", *streamConcurrency)
+	}
+
+	if *warmFrom < 0 || *warmTo < 0 || (*warmTo > 0 && *warmTo <= *warmFrom) {
+		log.Fatalf("⚠️ Invalid time window: -warm-to must be after -warm-from")
+	}
+
 	// Create warmer with config
-	config := Config{
-		Workers:    *workers,
-		Referer:    *referer,
-		Origin:     *origin,
-		PlaybackID: *playbackID,
-		Interval:   *interval,
-		TTL:        *ttl,
-		RewarmLast: *rewarmLast,
-		DaemonMode: *daemon,
-		Debug:      *debug,
-		Quiet:      *quiet,
+	config := hlswarm.Config{
+		Workers:            *workers,
+		PerHostWorkers:     *perHostWorkers,
+		AdaptiveWorkers:    *adaptiveWorkers,
+		MinWorkers:         *minWorkers,
+		MaxWorkers:         *maxWorkers,
+		Referer:            *referer,
+		Origin:             *origin,
+		PlaybackID:         *playbackID,
+		MinimalHeaders:     *minimalHeaders,
+		Interval:           *interval,
+		TTL:                *ttl,
+		RewarmLast:         *rewarmLast,
+		EdgeFirst:          *edgeFirstN,
+		SegmentsLimit:      *segmentsLimit,
+		MaxRetries:         *maxRetries,
+		RetryBaseDelay:     *retryDelay,
+		MaxRetryAfter:      *maxRetryAfter,
+		BreakerFailures:    *breakerFailures,
+		BreakerErrorRate:   *breakerErrorRate,
+		BreakerMaxBackoff:  *breakerMaxBackoff,
+		WebhookURL:         *webhookURL,
+		WebhookMinHitRatio: *webhookHitRatio,
+		WebhookInterval:    *webhookInterval,
+		StaleCycles:        *staleCycles,
+		CheckContinuity:    *checkContinuity,
+		Strict:             *strict,
+		MaxBodyBytes:       *maxBodyBytes,
+		MaxRedirects:       *maxRedirects,
+		PrefetchBytes:      *prefetchBytes,
+		AcceptEncoding:     *acceptEncoding,
+		Verify:             *verify,
+		VerifyDelay:        *verifyDelay,
+		CompareMethods:     *compareMethods,
+		Purge:              *purge,
+		PurgeMethod:        strings.ToUpper(*purgeMethod),
+		PurgeHeaders:       purgeHeaders,
+		MaxDuration:        *maxDuration,
+		CheckContentType:   *checkContentType,
+		PredictAhead:       *predictAhead,
+		LiveEdge:           *liveEdge,
+		MinInterval:        *minInterval,
+		MaxInterval:        *maxInterval,
+		Jitter:             *jitter,
+		WarmFrom:           *warmFrom,
+		WarmTo:             *warmTo,
+		MinSegmentBytes:    *minSegmentBytes,
+		ConnectTimeout:     *connectTimeout,
+		RequestTimeout:     *requestTimeout,
+		WarmUntilHit:       *warmUntilHit,
+		WarmUntilHitDelay:  *untilHitDelay,
+		Headers:            headers,
+		RedactHeaders:      redactHeaders,
+		Cookies:            cookies,
+		Method:             *method,
+		CacheHeader:        *cacheHeader,
+		CacheHitValue:      *cacheHitValue,
+		DisableAgeFallback: *noAgeFallback,
+		Rate:               *rateLimit,
+		Pace:               *pace,
+		DaemonMode:         *daemon,
+		Debug:              *debug,
+		LogLevel:           level,
+		LogFormat:          *logFormat,
+		Quiet:              *quiet,
+		Outputs:            outputs,
+		MetricsAddr:        *metricsAddr,
+		StateFile:          *stateFile,
+		APIAddr:            *apiAddr,
+		MaxTrackedSegments: *maxTracked,
+		DedupIgnoreQuery:   splitList(*dedupIgnore),
 	}
 
-	warmer := NewHLSWarmer(config)
+	// URLs come from the arguments, where "-" reads a list from stdin, and from
+	// -url-file; like positional arguments, listed URLs may end in "@interval"
+	args := flag.Args()
+	if *urlFile != "" {
+		listed, err := readListFile(*urlFile)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid -url-file: %v", err)
+		}
+		args = append(args, listed...)
+	}
+	if slices.Contains(args, "-") {
+		listed, err := readList(os.Stdin)
+		if err != nil {
+			log.Fatalf("⚠️ Error reading URLs from stdin: %v", err)
+		}
+		args = slices.DeleteFunc(args, func(arg string) bool { return arg == "-" })
+		args = append(args, listed...)
+	}
 
-	// Print configuration
-	if *referer != "" {
-		fmt.Printf("🔗 Using Referer: %s\n", *referer)
+	// Positional arguments may carry their own check interval as "url@10s"
+	var m3u8URLs []string
+	argIntervals := make(map[string]time.Duration)
+	for _, arg := range args {
+		m3u8URL, streamInterval := splitStreamInterval(arg)
+		m3u8URL = localPlaylistURL(m3u8URL)
+		if slices.Contains(m3u8URLs, m3u8URL) {
+			continue
+		}
+		m3u8URLs = append(m3u8URLs, m3u8URL)
+		if streamInterval > 0 {
+			argIntervals[m3u8URL] = streamInterval
+		}
+	}
+
+	// Merge the config file, letting explicitly set flags take precedence
+	var reloadStreams func() ([]hlswarm.Stream, error)
+	if *configPath != "" {
+		fileConfig, err := hlswarm.LoadConfigFile(*configPath)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid config file: %v", err)
+		}
+
+		overridden := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { overridden[f.Name] = true })
+		fileConfig.Apply(&config, overridden)
+
+		for _, m3u8URL := range fileConfig.URLs() {
+			if !slices.Contains(m3u8URLs, m3u8URL) {
+				m3u8URLs = append(m3u8URLs, m3u8URL)
+			}
+		}
+
+		// Reloads apply the same overrides as startup
+		intervals := maps.Clone(argIntervals)
+		reloadStreams = func() ([]hlswarm.Stream, error) {
+			return loadFileStreams(*configPath, overridden, intervals)
+		}
+		config.KeepAlive = *daemon
+	}
+
+	config.Streams = applyStreamIntervals(config.Streams, argIntervals)
+
+	// -workers may come from the config file, so the adaptive range is checked against it here
+	if *adaptiveWorkers {
+		workerCount := cmp.Or(config.Workers, hlswarm.DefaultWorkers)
+		if *minWorkers > workerCount || (*maxWorkers > 0 && *maxWorkers < workerCount) {
+			log.Fatalf("⚠️ Invalid -adaptive-workers range: -workers %d must be between -min-workers %d and -max-workers %d",
+				workerCount, *minWorkers, cmp.Or(*maxWorkers, workerCount*hlswarm.DefaultMaxWorkersFactor))
+		}
+	}
+
+	if err := hlswarm.ValidateSuccessCriteria(*successCriteria); err != nil {
+		log.Fatalf("⚠️ Invalid -success-criteria %q: %v", *successCriteria, err)
+	}
+	config.SuccessCriteria = *successCriteria
+
+	if _, err := hlswarm.ParseVariantSelector(*variant); err != nil {
+		log.Fatalf("⚠️ Invalid -variant %q: %v", *variant, err)
+	}
+	config.Variant = *variant
+
+	selectedMediaTypes, err := hlswarm.ParseMediaTypes(splitList(*mediaTypesFlag))
+	if err != nil {
+		log.Fatalf("⚠️ Invalid -media-types %q: %v", *mediaTypesFlag, err)
+	}
+	config.MediaTypes = selectedMediaTypes
+
+	if *userAgentFile != "" {
+		listed, err := readListFile(*userAgentFile)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid -user-agent-file: %v", err)
+		}
+		userAgents = append(userAgents, listed...)
+	}
+	for key := range headers {
+		if strings.EqualFold(key, "User-Agent") && len(userAgents) > 0 {
+			log.Fatalf("⚠️ -user-agent cannot be combined with -header User-Agent")
+		}
+	}
+	config.UserAgents = userAgents
+
+	// An empty list disables keywords rather than falling back to the defaults
+	config.SegmentKeywords = append([]string{}, splitList(*segmentKeywords)...)
+	config.NoSegmentFilter = *noSegmentFilter
+
+	if *baseURL != "" {
+		parsedURL, err := url.Parse(*baseURL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			log.Fatalf("⚠️ Invalid -base-url %q: must be an http or https URL", *baseURL)
+		}
+		config.BaseURL = *baseURL
+	}
+
+	if *basicAuth != "" {
+		userinfo, err := parseBasicAuth(*basicAuth)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid -basic-auth: %v", err)
+		}
+		config.BasicAuth = userinfo
+	}
+
+	config.AllowHosts = allowHosts
+	config.DenyHosts = denyHosts
+
+	config.EdgeIPs = edgeIPs
+	config.Mirrors = mirrors
+	if len(edgeIPs) > 0 && *proxy != "" {
+		log.Fatalf("⚠️ -edge-ip cannot be combined with -proxy")
+	}
+
+	switch *ipVersion {
+	case "", hlswarm.IPVersion4, hlswarm.IPVersion6, hlswarm.IPVersionBoth:
+	default:
+		log.Fatalf("⚠️ Invalid -ip-version %q: must be %s, %s or %s", *ipVersion, hlswarm.IPVersion4, hlswarm.IPVersion6, hlswarm.IPVersionBoth)
+	}
+	if *ipVersion != "" && len(edgeIPs) > 0 {
+		log.Fatalf("⚠️ -ip-version cannot be combined with -edge-ip")
+	}
+	config.IPVersion = *ipVersion
+	config.HTTP1Only = *http1Only
+
+	tlsConfig, err := loadTLSConfig(*insecureSkipVerify, *caFile, *clientCert, *clientKey)
+	if err != nil {
+		log.Fatalf("⚠️ Invalid TLS configuration: %v", err)
+	}
+	config.TLSConfig = tlsConfig
+
+	if *proxy != "" {
+		proxyURL, err := parseProxyURL(*proxy)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid -proxy: %v", err)
+		}
+		config.Proxy = proxyURL
+	}
+
+	if *cookieFile != "" {
+		jar, err := loadCookieFile(*cookieFile)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid cookie file: %v", err)
+		}
+		config.CookieJar = jar
+	}
+
+	if slices.Contains(outputs, hlswarm.OutputCSV) {
+		report, err := hlswarm.OpenCSVReport(*reportFile)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid -report-file: %v", err)
+		}
+		// Rows are flushed as they are written, so the file is left for exit to close
+		config.CSVReport = report
+	}
+
+	if *otlpEndpoint != "" {
+		parsedURL, err := url.Parse(*otlpEndpoint)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			log.Fatalf("⚠️ Invalid -otlp-endpoint %q: must be an http or https URL", *otlpEndpoint)
+		}
+		provider, err := hlswarm.NewTracerProvider(*otlpEndpoint)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid -otlp-endpoint: %v", err)
+		}
+		config.TracerProvider = provider
 	}
-	if *origin != "" {
-		fmt.Printf("🌐 Using Origin: %s\n", *origin)
+
+	warmer := hlswarm.New(config)
+
+	if *pruneState {
+		kept, pruned, err := warmer.PruneState()
+		if err != nil {
+			log.Fatalf("⚠️ Error pruning state file: %v", err)
+		}
+		warmer.Logger().Info("Pruned state file", hlswarm.Icon("🧹"), "file", *stateFile, "pruned", pruned, "kept", kept)
+		return
 	}
-	fmt.Printf("🎯 Playback Session ID: %s\n", warmer.GetPlaybackSessionID())
 
-	m3u8URLs := flag.Args()
+	// Print configuration
+	if config.Referer != "" {
+		warmer.Logger().Info("Using Referer", hlswarm.Icon("🔗"), "referer", config.Referer)
+	}
+	if config.Origin != "" {
+		warmer.Logger().Info("Using Origin", hlswarm.Icon("🌐"), "origin", config.Origin)
+	}
+	if config.Proxy != nil {
+		warmer.Logger().Info("Using proxy", hlswarm.Icon("🧭"), "proxy", config.Proxy.Redacted())
+	}
+	if *insecureSkipVerify {
+		warmer.Logger().Warn("TLS certificate verification is disabled")
+	}
+	if id := warmer.GetPlaybackSessionID(); id != "" {
+		warmer.Logger().Info("Playback Session ID", hlswarm.Icon("🎯"), "playback_id", id)
+	}
 
-	if *daemon {
-		runDaemonMode(warmer, m3u8URLs)
-	} else {
-		runOnceMode(warmer, m3u8URLs)
+	status := &exitStatus{minHitRatio: *minHitRatio}
+	switch {
+	case *onceThenExit:
+		runCycleMode(warmer, m3u8URLs, status)
+	case *daemon:
+		runDaemonMode(warmer, m3u8URLs, reloadStreams)
+		warmer.FlushTraces()
+		return
+	default:
+		runOnceMode(warmer, m3u8URLs, *streamConcurrency, status)
 	}
+	warmer.FlushTraces()
+	os.Exit(status.code())
 }
 
 func printHelp() {
-	fmt.Println("HLS Proxy Warmer - Cache M3U8 playlists and segments")
+	fmt.Println("HLS Proxy Warmer - Cache M3U8 playlists, DASH manifests and their segments")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Printf("  %s [options] <m3u8_url1> [m3u8_url2] ...\n", os.Args[0])
+	fmt.Printf("  %s [options] <m3u8_url1[@interval]> [m3u8_url2[@interval]] ...\n", os.Args[0])
+	fmt.Printf("  %s [options] -config streams.yaml\n", os.Args[0])
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -referer string     Referer header to send with requests")
 	fmt.Println("  -origin string      Origin header to send with requests")
 	fmt.Println("  -playback-id string X-Playback-Session-Id header (auto-generated if not provided)")
-	fmt.Printf("  -workers int        Number of parallel workers (default %d)\n", defaultWorkers)
+	fmt.Println("  -minimal-headers    Send only Accept and explicitly configured headers instead of emulating a browser (default: browser headers)")
+	fmt.Println("  -header string      Extra request header as \"Key: Value\", overriding defaults (repeatable)")
+	fmt.Println("  -cookie string      Cookie sent with every request as \"name=value\" (repeatable)")
+	fmt.Println("  -cookie-file string Netscape-format cookie jar file to load")
+	fmt.Println("  -redact-header string  Header whose value is hidden in debug logs, besides Authorization, Cookie and other credentials (repeatable)")
+	fmt.Printf("  -segment-keywords string  Comma-separated keywords marking playlist lines without a \".\" as segments (default %q)\n", strings.Join(hlswarm.DefaultSegmentKeywords, ","))
+	fmt.Println("  -no-segment-filter  Treat every non-comment playlist line as a segment URL")
+	fmt.Println("  -base-url string    URL that relative references in local playlist files resolve against,")
+	fmt.Println("                      e.g. https://cdn.example.com/stream/")
+	fmt.Println("  -basic-auth string  HTTP Basic credentials as \"user:password\", sent to each playlist's host only")
+	fmt.Println("  -user-agent string  User-Agent to send, rotated round-robin across requests when repeated")
+	fmt.Println("  -user-agent-file string  File of User-Agents to rotate through, one per line")
+	fmt.Println("  -allow-host string  Only fetch segments and variant playlists from this host or *.domain (repeatable)")
+	fmt.Println("  -deny-host string   Never fetch segments or variant playlists from this host or *.domain (repeatable)")
+	fmt.Println("  -edge-ip string     Edge IP to warm every segment against, keeping the URL's Host and TLS name (repeatable)")
+	fmt.Println("  -mirror string      Origin host mirroring the content as \"host[:port][=weight]\"; segment requests")
+	fmt.Println("                      are spread across mirrors by weight, reporting hits per mirror (repeatable)")
+	fmt.Println("  -insecure-skip-verify  Do not verify TLS certificates (testing only)")
+	fmt.Println("  -ca-file string     PEM bundle of CA certificates to verify TLS connections against")
+	fmt.Println("  -client-cert string PEM client certificate for mutual TLS (requires -client-key)")
+	fmt.Println("  -client-key string  PEM private key for -client-cert")
+	fmt.Println("  -http1-only         Disable HTTP/2 and warm over HTTP/1.1 only")
+	fmt.Println("  -ip-version string  Address family to connect over: 4, 6, or both to warm every segment over each")
+	fmt.Println("  -proxy string       Proxy URL (http://, https:// or socks5://); defaults to HTTP_PROXY/HTTPS_PROXY/NO_PROXY")
+	fmt.Printf("  -workers int        Number of parallel workers (default %d)\n", hlswarm.DefaultWorkers)
+	fmt.Println("  -adaptive-workers   Resize each stream's workers every daemon cycle based on p95 latency and error rate")
+	fmt.Println("  -min-workers int    Fewest workers -adaptive-workers scales a stream down to (default 1)")
+	fmt.Printf("  -max-workers int    Most workers -adaptive-workers scales a stream up to (default %d times -workers)\n", hlswarm.DefaultMaxWorkersFactor)
+	fmt.Println("  -per-host-workers int  Maximum concurrent segment requests per host across all streams (0 = unlimited)")
+	fmt.Println("  -stream-concurrency int  Number of playlists warmed in parallel in one-shot mode, each with its own workers (default 1)")
+	fmt.Println("  -method string      HTTP method used to warm segments: GET or HEAD (default GET)")
+	fmt.Println("  -cache-header string Response header that decides cache hits, replacing the built-in heuristics")
+	fmt.Printf("  -cache-hit-value string Case-insensitive substring of -cache-header that means a hit (default %q)\n", hlswarm.DefaultCacheHitValue)
+	fmt.Println("  -no-age-fallback    Do not count a non-zero Age header as a cache hit")
+	fmt.Println("  -rate float         Maximum requests per second across all workers (0 = unlimited)")
+	fmt.Println("  -pace               In daemon mode, spread each cycle's requests evenly over the polling interval instead of bursting")
 	fmt.Println("  -daemon             Run in daemon mode (continuously)")
-	fmt.Printf("  -interval duration  Check interval for daemon mode (default %v)\n", defaultInterval)
+	fmt.Println("  -once-then-exit     Run a single daemon cycle per stream, warming only new segments, then exit")
+	fmt.Println("                      (use with -state-file to skip segments already warmed in earlier runs)")
+	fmt.Println("  -min-hit-ratio float  Exit with code 4 if a stream's success ratio (0-1) under -success-criteria is below this")
+	fmt.Printf("  -success-criteria string  What counts as a successful segment: cache-hit, status-2xx or both (default %q)\n", hlswarm.SuccessCacheHit)
+	fmt.Printf("  -interval duration  Check interval for daemon mode (default: half of EXT-X-TARGETDURATION, else %v)\n", hlswarm.DefaultInterval)
+	fmt.Println("  -metrics-addr string Address to serve Prometheus metrics on in daemon mode (e.g. :9090)")
+	fmt.Println("  -otlp-endpoint string  OTLP/HTTP endpoint to export trace spans to (e.g. http://localhost:4318)")
 	fmt.Println("  -rewarm-last int    Rewarm last N segments every cycle")
-	fmt.Printf("  -ttl duration       How long before a processed segment is considered stale (default %v)\n", defaultTTL)
-	fmt.Println("  -debug              Show debug information including headers")
+	fmt.Println("  -edge-first int     Warm the newest N segments of each playlist (the live edge) first")
+	fmt.Println("  -segments-limit int  Warm at most the newest N media segments per run or daemon cycle, leaving the rest")
+	fmt.Println("                      for later cycles (0 = unlimited)")
+	fmt.Println("  -variant string     Master playlist variants to warm: all, lowest, highest, or a bandwidth in bits/s to warm")
+	fmt.Println("                      the highest variant not above it, with its renditions (default all)")
+	fmt.Println("  -media-types string  Comma-separated EXT-X-MEDIA rendition types to warm: audio, video, subtitles,")
+	fmt.Println("                      closed-captions (default all)")
+	fmt.Printf("  -ttl duration       How long before a processed segment is considered stale (default %v)\n", hlswarm.DefaultTTL)
+	fmt.Printf("  -max-retries int    Retries for segments failing with network errors, 5xx or 429 (default %d)\n", hlswarm.DefaultMaxRetries)
+	fmt.Printf("  -retry-base-delay duration  Initial retry delay, doubled on each attempt (default %v)\n", hlswarm.DefaultRetryBaseDelay)
+	fmt.Println("  -warm-until-hit int Re-request missed segments up to N times until they are cache hits")
+	fmt.Printf("  -warm-until-hit-delay duration  Delay between re-requests in -warm-until-hit mode (default %v)\n", hlswarm.DefaultWarmUntilHitDelay)
+	fmt.Println("  -verify             After warming, wait -verify-delay and re-request every segment to check it is still cached")
+	fmt.Printf("  -verify-delay duration  Delay before the -verify pass (default %v)\n", hlswarm.DefaultVerifyDelay)
+	fmt.Println("  -purge              Before warming, send a purge request for every segment so the warm fetches fresh content")
+	fmt.Printf("  -purge-method string  HTTP method of -purge requests (default %q)\n", hlswarm.DefaultPurgeMethod)
+	fmt.Println("  -purge-header string  Header sent with -purge requests only, e.g. an API token, as \"Key: Value\" (repeatable)")
+	fmt.Println("  -compare-methods int  Before warming, request this many sampled segments with HEAD, a ranged GET and GET and compare their cache status (0 disables)")
+	fmt.Println("  -max-duration duration  Stop a one-shot warm after this long and report the partial result (0 = unlimited)")
+	fmt.Printf("  -max-retry-after duration   Maximum delay honored from a Retry-After header (default %v)\n", hlswarm.DefaultMaxRetryAfter)
+	fmt.Println("  -breaker-failures int  In daemon mode, back off a stream after N consecutive failed cycles (0 = disabled)")
+	fmt.Printf("  -breaker-error-rate float  Share of errored segments (0-1) that fails a cycle (default %v)\n", hlswarm.DefaultBreakerErrorRate)
+	fmt.Printf("  -breaker-max-backoff duration  Longest pause of a stream whose circuit is open (default %v)\n", hlswarm.DefaultBreakerMaxBackoff)
+	fmt.Println("  -webhook-url string In daemon mode, POST a JSON notification to this URL when a stream's cycle fails")
+	fmt.Println("  -webhook-min-hit-ratio float  Also notify when a cycle's hit ratio (0-1) is below this (0 = failures only)")
+	fmt.Printf("  -webhook-interval duration  Shortest time between two notifications for the same stream (default %v)\n", hlswarm.DefaultWebhookInterval)
+	fmt.Println("  -check-continuity   Report playlist segments that 404 and, in daemon mode, media sequence gaps between cycles")
+	fmt.Println("  -strict             Fail on malformed playlists instead of warning")
+	fmt.Println("  -stale-cycles int   In daemon mode, warn when a live stream finds no new segments for N consecutive cycles (0 = disabled)")
+	fmt.Printf("  -connect-timeout duration   Timeout for establishing a connection, including the TLS handshake (default %v)\n", hlswarm.DefaultConnectTimeout)
+	fmt.Printf("  -request-timeout duration   Timeout for a whole request, including reading the body (default %v)\n", hlswarm.DefaultRequestTimeout)
+	fmt.Println("  -max-body-bytes int Abort segment downloads larger than this many bytes (0 = unlimited)")
+	fmt.Println("  -accept-encoding string  Accept-Encoding for segment requests, e.g. identity, gzip or br")
+	fmt.Println("                      (default: gzip)")
+	fmt.Println("  -prefetch-bytes int Only request the first N bytes of each segment with a Range header (0 = whole segments)")
+	fmt.Printf("  -max-redirects int  Maximum redirects followed per request before it fails (default %d)\n", hlswarm.DefaultMaxRedirects)
+	fmt.Println("  -warm-from duration Only warm media segments from this playback time on, e.g. 5m")
+	fmt.Println("  -warm-to duration   Only warm media segments before this playback time (0 = until the end)")
+	fmt.Println("  -live-edge          In daemon mode, warm only the newest segment of each live playlist, polling again when its EXTINF duration has passed")
+	fmt.Println("  -min-interval duration  With -max-interval, the shortest daemon polling interval while new segments keep appearing (default: -interval)")
+	fmt.Println("  -max-interval duration  In daemon mode, back off polling toward this interval while a live stream finds no new segments (0 = fixed interval)")
+	fmt.Println("  -jitter float       Randomize each daemon polling interval by up to ±this fraction (0-1), staggering streams")
+	fmt.Println("  -predict-ahead int  In daemon mode, speculatively warm N numbered segments past the live edge")
+	fmt.Println("  -check-content-type Flag segment responses whose Content-Type is not a media type as suspicious")
+	fmt.Println("  -min-segment-bytes int  Flag media segment responses smaller than this many bytes as suspicious (0 = disabled)")
+	fmt.Println("  -debug              Show debug information including headers (same as -log-level debug)")
+	fmt.Println("  -log-level string   Minimum log level: debug, info, warn or error (default info)")
+	fmt.Println("  -log-format string  Log format: text or json (default text)")
 	fmt.Println("  -quiet              Suppress detailed output (only show summary)")
+	fmt.Println("  -output string      Result formats, comma-separated: text, json and csv (default text)")
+	fmt.Println("  -report-file string File to append CSV rows to with -output csv, one per segment (default stdout)")
+	fmt.Println("  -api-addr string    Address to serve the HTTP control API on in daemon mode (e.g. :8080)")
+	fmt.Println("  -dedup-ignore-query string  Comma-separated query parameters to ignore when detecting new segments (\"*\" = all)")
+	fmt.Println("  -max-tracked-segments int  Maximum processed segments remembered in daemon mode (0 = only expire after -ttl)")
+	fmt.Println("  -state-file string  File to persist processed segments to across daemon restarts")
+	fmt.Println("  -prune-state        Remove -state-file entries older than -ttl, report how many were pruned and exit without warming")
+	fmt.Println("  -url-file string    File with one playlist URL per line (# comments allowed); - reads stdin")
+	fmt.Println("  -config string      Path to a YAML or JSON file describing streams and their settings (reloaded on SIGHUP)")
 	fmt.Println("  -help               Show this help message")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Printf("  %s https://example.com/playlist.m3u8\n", os.Args[0])
 	fmt.Printf("  %s -daemon -interval 15s https://example.com/playlist.m3u8\n", os.Args[0])
+	fmt.Printf("  %s -daemon https://example.com/live.m3u8@2s https://example.com/vod.m3u8@30s\n", os.Args[0])
 	fmt.Printf("  %s -referer \"https://example.com/\" https://example.com/playlist.m3u8\n", os.Args[0])
-	fmt.Printf("  %s -workers 20 https://example.com/\n", os.Args[0])
+	fmt.Printf("  %s -header \"Authorization: Bearer TOKEN\" -header \"X-Token: abc\" https://example.com/playlist.m3u8\n", os.Args[0])
+	fmt.Printf("  %s -workers 20 https://example.com/stream/   (directory: its master/index/playlist.m3u8 or linked playlists)\n", os.Args[0])
+	fmt.Printf("  %s -daemon -config streams.yaml\n", os.Args[0])
+	fmt.Printf("  cat catalog.txt | %s -stream-concurrency 8 -\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("Exit codes (one-shot and -once-then-exit):")
+	fmt.Println("  0 success, 1 usage or runtime error, 2 playlist failed, 3 segments errored, 4 hit ratio below -min-hit-ratio")
+	fmt.Println()
+	fmt.Println("Config file (YAML or JSON; command-line flags override file values):")
+	fmt.Println("  interval: 10s")
+	fmt.Println("  streams:")
+	fmt.Println("    - url: https://example.com/live.m3u8")
+	fmt.Println("      referer: https://example.com/")
+	fmt.Println("      interval: 2s")
+	fmt.Println("      ttl: 5m")
+	fmt.Println("      rewarm_last: 3")
+}
+
+// applyStreamIntervals sets per-stream intervals given on the command line, which
+// take precedence over intervals from a config file
+func applyStreamIntervals(streams []hlswarm.Stream, intervals map[string]time.Duration) []hlswarm.Stream {
+	for i := range streams {
+		if interval, ok := intervals[streams[i].URL]; ok {
+			streams[i].Interval = interval
+			delete(intervals, streams[i].URL)
+		}
+	}
+	for m3u8URL, interval := range intervals {
+		streams = append(streams, hlswarm.Stream{URL: m3u8URL, Interval: interval})
+	}
+	return streams
+}
+
+// loadFileStreams re-reads the stream entries of a config file, applying flag and
+// command-line interval overrides
+func loadFileStreams(path string, overridden map[string]bool, intervals map[string]time.Duration) ([]hlswarm.Stream, error) {
+	fileConfig, err := hlswarm.LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config hlswarm.Config
+	fileConfig.Apply(&config, overridden)
+	for i := range config.Streams {
+		if interval, ok := intervals[config.Streams[i].URL]; ok {
+			config.Streams[i].Interval = interval
+		}
+	}
+	return config.Streams, nil
 }
 
-func runDaemonMode(warmer *HLSWarmer, m3u8URLs []string) {
+func runDaemonMode(warmer *hlswarm.HLSWarmer, m3u8URLs []string, reloadStreams func() ([]hlswarm.Stream, error)) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -104,28 +783,110 @@ func runDaemonMode(warmer *HLSWarmer, m3u8URLs []string) {
 
 	go func() {
 		<-sigChan
-		fmt.Println("\n🔄 Shutting down gracefully...")
+		warmer.Logger().Info("Shutting down gracefully...", hlswarm.Icon("🔄"))
 		cancel()
 	}()
 
+	// Reload the config file's streams on SIGHUP
+	if reloadStreams != nil {
+		// The file was already loaded at startup, so this only fails if it changed since
+		current, _ := reloadStreams()
+
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-hupChan:
+					streams, err := reloadStreams()
+					if err != nil {
+						warmer.Logger().Warn("Config reload failed, keeping current streams", "error", err)
+						continue
+					}
+					warmer.ReloadStreams(ctx, current, streams)
+					current = streams
+				}
+			}
+		}()
+	}
+
 	// Run daemon
 	err := warmer.RunDaemon(ctx, m3u8URLs)
 	if err != nil && err != context.Canceled {
-		log.Printf("⚠️ Daemon error: %v", err)
+		warmer.Logger().Error("Daemon error", "error", err)
+	}
+}
+
+func runCycleMode(warmer *hlswarm.HLSWarmer, m3u8URLs []string, status *exitStatus) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	outcomes, err := warmer.RunCycle(ctx, m3u8URLs)
+	if err != nil {
+		warmer.Logger().Error("Cycle error", "error", err)
+		os.Exit(1)
+	}
+	for _, outcome := range outcomes {
+		status.record(outcome.Result, outcome.Err)
 	}
+	warmer.Logger().Info("Cycle complete", hlswarm.Icon("🏁"), "streams", len(m3u8URLs))
 }
 
-func runOnceMode(warmer *HLSWarmer, m3u8URLs []string) {
-	for _, m3u8URL := range m3u8URLs {
-		fmt.Printf("\n🚀 Processing %s...\n", m3u8URL)
+func runOnceMode(warmer *hlswarm.HLSWarmer, m3u8URLs []string, concurrency int, status *exitStatus) {
+	// Abort outstanding requests on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Warm up to concurrency playlists at once; results are printed in the order
+	// the URLs were given, each as soon as it and every earlier one are done
+	type outcome struct {
+		result *hlswarm.WarmResult
+		err    error
+		done   chan struct{}
+	}
+	outcomes := make([]*outcome, len(m3u8URLs))
+	for i := range outcomes {
+		outcomes[i] = &outcome{done: make(chan struct{})}
+	}
+
+	sem := make(chan struct{}, concurrency)
+	go func() {
+		for i, m3u8URL := range m3u8URLs {
+			out := outcomes[i]
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				out.err = ctx.Err()
+				close(out.done)
+				continue
+			}
+			go func() {
+				defer func() { <-sem }()
+				defer close(out.done)
+				warmer.Logger().Info("Processing", hlswarm.Icon("🚀"), "stream", m3u8URL)
+				out.result, out.err = warmer.WarmM3U8(ctx, m3u8URL)
+			}()
+		}
+	}()
+
+	for i, m3u8URL := range m3u8URLs {
+		out := outcomes[i]
+		<-out.done
+		if ctx.Err() != nil {
+			warmer.Logger().Info("Interrupted", hlswarm.Icon("🛑"))
+			os.Exit(1)
+		}
 
-		result, err := warmer.WarmM3U8(m3u8URL)
+		result, err := out.result, out.err
+		status.record(result, err)
 		if err != nil {
-			log.Printf("⚠️ Error: %v", err)
+			warmer.Logger().Warn("Error", "stream", m3u8URL, "error", err)
 			continue
 		}
 
-		warmer.PrintResults(result)
-		fmt.Println("\n" + strings.Repeat("=", 50))
+		warmer.RecordResult(result)
 	}
 }
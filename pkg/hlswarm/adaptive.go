@@ -0,0 +1,91 @@
+package hlswarm
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// adaptiveErrorRate is the share of failed requests in a cycle above which
+	// -adaptive-workers halves a stream's workers
+	adaptiveErrorRate = 0.05
+	// adaptiveLatencyFactor is how many times its best p95 latency a stream's p95
+	// may grow to before -adaptive-workers takes it for a struggling origin
+	adaptiveLatencyFactor = 2
+	// DefaultMaxWorkersFactor sets the -max-workers default as a multiple of -workers
+	DefaultMaxWorkersFactor = 4
+)
+
+// workerScale is the adaptive worker count of a daemon stream
+type workerScale struct {
+	workers int
+	// bestP95 is the lowest p95 latency seen, taken as the stream's healthy latency
+	bestP95 time.Duration
+}
+
+// streamWorkers returns the number of workers to warm a stream's segments with:
+// its adapted count in -adaptive-workers mode, -workers otherwise
+func (h *HLSWarmer) streamWorkers(stream string) int {
+	if !h.adaptiveWorkers {
+		return h.maxWorkers
+	}
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	if scale, ok := h.streamScales[stream]; ok {
+		return scale.workers
+	}
+	return h.maxWorkers
+}
+
+// adaptWorkers resizes a stream's worker pool after a cycle. Errors above
+// adaptiveErrorRate halve it and a p95 latency well above the best seen shrinks it
+// by a quarter; otherwise it doubles, always staying within -min-workers and
+// -max-workers.
+func (h *HLSWarmer) adaptWorkers(stream string, results []CacheStatus) {
+	if !h.adaptiveWorkers || len(results) == 0 {
+		return
+	}
+
+	failed := 0
+	latencies := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if r.Error != nil || r.StatusCode >= 500 || r.StatusCode == http.StatusTooManyRequests {
+			failed++
+		}
+		latencies = append(latencies, r.Duration)
+	}
+	slices.Sort(latencies)
+	p95 := percentile(latencies, 95)
+	errorRate := float64(failed) / float64(len(results))
+
+	h.streamMu.Lock()
+	scale, ok := h.streamScales[stream]
+	if !ok {
+		scale = &workerScale{workers: h.maxWorkers}
+		h.streamScales[stream] = scale
+	}
+	previous := scale.workers
+	switch {
+	case errorRate > adaptiveErrorRate:
+		scale.workers = max(scale.workers/2, h.minWorkers)
+	case scale.bestP95 > 0 && p95 > scale.bestP95*adaptiveLatencyFactor:
+		scale.workers = max(scale.workers-scale.workers/4, h.minWorkers)
+	default:
+		scale.workers = min(scale.workers*2, h.workerCeiling)
+	}
+	if scale.bestP95 == 0 || p95 < scale.bestP95 {
+		scale.bestP95 = p95
+	}
+	workers := scale.workers
+	h.streamMu.Unlock()
+
+	switch {
+	case workers > previous:
+		h.log.Info("Scaled workers up", Icon("📈"), "stream", stream, "workers", workers, "previous", previous,
+			"p95", p95.Round(time.Millisecond), "error_rate", errorRate)
+	case workers < previous:
+		h.log.Info("Scaled workers down", Icon("📉"), "stream", stream, "workers", workers, "previous", previous,
+			"p95", p95.Round(time.Millisecond), "error_rate", errorRate)
+	}
+}
@@ -0,0 +1,223 @@
+package hlswarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// apiReadHeaderTimeout bounds how long a control API client may take to send
+	// its request headers
+	apiReadHeaderTimeout = 10 * time.Second
+	// apiWriteTimeout bounds how long a control API response may take, so a stalled
+	// client does not hold a connection forever
+	apiWriteTimeout = 30 * time.Second
+)
+
+// jsonStream is the control API's view of a stream warmed by the daemon
+type jsonStream struct {
+	URL          string      `json:"url"`
+	Interval     string      `json:"interval"`
+	AutoInterval bool        `json:"auto_interval,omitempty"`
+	Warming      bool        `json:"warming"`
+	IdleCycles   int         `json:"idle_cycles"`
+	Stale        bool        `json:"stale"`
+	Stats        StreamStats `json:"stats"`
+	LastResult   *jsonResult `json:"last_result,omitempty"`
+}
+
+// serveAPI exposes the daemon's control API on the configured address until ctx
+// is cancelled. Streams added through the API are warmed until removed or ctx ends.
+func (h *HLSWarmer) serveAPI(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /streams", h.handleListStreams)
+	mux.HandleFunc("POST /streams", func(w http.ResponseWriter, r *http.Request) {
+		h.handleAddStream(ctx, w, r)
+	})
+	mux.HandleFunc("DELETE /streams", h.handleRemoveStream)
+	mux.HandleFunc("GET /results", h.handleResults)
+
+	server := &http.Server{
+		Addr:              h.apiAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: apiReadHeaderTimeout,
+		WriteTimeout:      apiWriteTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		h.log.Warn("Control API server error", "error", err)
+	}
+}
+
+// handleListStreams lists the streams currently warmed by the daemon
+func (h *HLSWarmer) handleListStreams(w http.ResponseWriter, r *http.Request) {
+	h.streamMu.Lock()
+	urls := make([]string, 0, len(h.streamRuns))
+	for m3u8URL := range h.streamRuns {
+		urls = append(urls, m3u8URL)
+	}
+	h.streamMu.Unlock()
+	slices.Sort(urls)
+
+	streams := make([]jsonStream, 0, len(urls))
+	for _, m3u8URL := range urls {
+		stream := h.streamFor(m3u8URL)
+		entry := jsonStream{
+			URL:          m3u8URL,
+			Interval:     h.streamInterval(stream).String(),
+			AutoInterval: stream.AutoInterval,
+		}
+
+		h.streamMu.Lock()
+		entry.Warming = h.streamActive[m3u8URL]
+		result := h.streamResults[m3u8URL]
+		h.streamMu.Unlock()
+		entry.Stats = h.StreamStats(m3u8URL)
+		entry.IdleCycles, entry.Stale = h.streamIdleCycles(m3u8URL)
+
+		if result != nil {
+			out := newJSONResult(result)
+			entry.LastResult = &out
+		}
+		streams = append(streams, entry)
+	}
+
+	writeJSON(w, http.StatusOK, streams)
+}
+
+// handleAddStream starts warming a stream described by a JSON body such as
+// {"url": "https://example.com/live.m3u8", "interval": "2s"}
+func (h *HLSWarmer) handleAddStream(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var fs FileStream
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fs); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid stream: %v", err))
+		return
+	}
+
+	fs.URL = strings.TrimSpace(fs.URL)
+	if !isHTTPURL(fs.URL) {
+		writeError(w, http.StatusBadRequest, "url must be an http or https URL")
+		return
+	}
+	if fs.RewarmLast < 0 {
+		writeError(w, http.StatusBadRequest, "rewarm_last must not be negative")
+		return
+	}
+	for _, mirror := range fs.Mirrors {
+		if err := mirror.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if err := fs.PlaylistRequest.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "playlist_request: "+err.Error())
+		return
+	}
+	if err := fs.SegmentRequest.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "segment_request: "+err.Error())
+		return
+	}
+
+	stream := Stream{
+		URL:             fs.URL,
+		Referer:         fs.Referer,
+		Origin:          fs.Origin,
+		Interval:        time.Duration(fs.Interval),
+		TTL:             time.Duration(fs.TTL),
+		RewarmLast:      fs.RewarmLast,
+		Mirrors:         fs.Mirrors,
+		PlaylistRequest: fs.PlaylistRequest,
+		SegmentRequest:  fs.SegmentRequest,
+	}
+	if !h.addStream(ctx, stream) {
+		writeError(w, http.StatusConflict, "stream is already being warmed")
+		return
+	}
+
+	h.log.Info("Stream added via control API", Icon("➕"), "stream", stream.URL)
+	writeJSON(w, http.StatusCreated, jsonStream{URL: stream.URL, Interval: h.streamFor(stream.URL).Interval.String()})
+}
+
+// handleRemoveStream stops warming the stream given by the url query parameter
+func (h *HLSWarmer) handleRemoveStream(w http.ResponseWriter, r *http.Request) {
+	m3u8URL := r.URL.Query().Get("url")
+	if m3u8URL == "" {
+		writeError(w, http.StatusBadRequest, "url query parameter is required")
+		return
+	}
+
+	if !h.removeStream(m3u8URL) {
+		writeError(w, http.StatusNotFound, "stream is not being warmed")
+		return
+	}
+
+	h.log.Info("Stream removed via control API", Icon("➖"), "stream", m3u8URL)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResults returns the latest warm result of every stream, or of the stream
+// given by the url query parameter
+func (h *HLSWarmer) handleResults(w http.ResponseWriter, r *http.Request) {
+	m3u8URL := r.URL.Query().Get("url")
+
+	// Copy the results under the lock but encode them after releasing it, so a
+	// slow client does not stall the streams recording their cycles
+	if m3u8URL != "" {
+		h.streamMu.Lock()
+		result, ok := h.streamResults[m3u8URL]
+		var out jsonResult
+		if ok {
+			out = newJSONResult(result)
+		}
+		h.streamMu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusNotFound, "no result for stream")
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+
+	h.streamMu.Lock()
+	urls := make([]string, 0, len(h.streamResults))
+	for url := range h.streamResults {
+		urls = append(urls, url)
+	}
+	slices.Sort(urls)
+
+	results := make([]jsonResult, 0, len(urls))
+	for _, url := range urls {
+		results = append(results, newJSONResult(h.streamResults[url]))
+	}
+	h.streamMu.Unlock()
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
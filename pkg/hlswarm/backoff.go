@@ -0,0 +1,83 @@
+package hlswarm
+
+import "time"
+
+// backoffQuietCycles is how many consecutive cycles without new segments it
+// takes before -max-interval doubles a stream's polling interval
+const backoffQuietCycles = 2
+
+// intervalBackoff is the adaptive polling interval of a daemon stream
+type intervalBackoff struct {
+	interval time.Duration
+	// quiet counts the cycles without new segments since the interval last changed
+	quiet int
+}
+
+// streamInterval returns how long to wait before a stream's next cycle: the wait
+// for its next edge segment with -live-edge, its adapted interval with
+// -max-interval, its configured interval otherwise
+func (h *HLSWarmer) streamInterval(stream Stream) time.Duration {
+	if h.liveEdge {
+		if wait, ok := h.liveEdgeWait(stream.URL); ok {
+			return wait
+		}
+	}
+	if h.maxInterval <= 0 {
+		return stream.Interval
+	}
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	if backoff, ok := h.streamBackoffs[stream.URL]; ok {
+		return backoff.interval
+	}
+	return h.clampInterval(stream)
+}
+
+// minIntervalFor returns the shortest interval a stream is polled at: -min-interval,
+// or the stream's own interval when that is not set
+func (h *HLSWarmer) minIntervalFor(stream Stream) time.Duration {
+	if h.minInterval > 0 {
+		return min(h.minInterval, h.maxInterval)
+	}
+	return min(stream.Interval, h.maxInterval)
+}
+
+// clampInterval returns the stream's configured interval within -min-interval and
+// -max-interval, where an adaptive interval starts
+func (h *HLSWarmer) clampInterval(stream Stream) time.Duration {
+	return min(max(stream.Interval, h.minIntervalFor(stream)), h.maxInterval)
+}
+
+// adaptInterval adjusts a live stream's polling interval after a cycle. One that
+// found new segments halves it, down to -min-interval, so a moving stream is
+// followed closely; every backoffQuietCycles consecutive cycles that found none
+// double it, up to -max-interval, so an idle stream costs few requests.
+func (h *HLSWarmer) adaptInterval(stream Stream, fresh int) {
+	if h.maxInterval <= 0 {
+		return
+	}
+
+	h.streamMu.Lock()
+	backoff, ok := h.streamBackoffs[stream.URL]
+	if !ok {
+		backoff = &intervalBackoff{interval: h.clampInterval(stream)}
+		h.streamBackoffs[stream.URL] = backoff
+	}
+	previous := backoff.interval
+	if fresh > 0 {
+		backoff.quiet = 0
+		backoff.interval = max(backoff.interval/2, h.minIntervalFor(stream))
+	} else if backoff.quiet++; backoff.quiet >= backoffQuietCycles {
+		backoff.quiet = 0
+		backoff.interval = min(backoff.interval*2, h.maxInterval)
+	}
+	interval := backoff.interval
+	h.streamMu.Unlock()
+
+	switch {
+	case interval < previous:
+		h.log.Info("New segments, polling faster", Icon("🐇"), "stream", stream.URL, "interval", interval, "previous", previous)
+	case interval > previous:
+		h.log.Info("No new segments, backing off", Icon("🐢"), "stream", stream.URL, "interval", interval, "previous", previous)
+	}
+}
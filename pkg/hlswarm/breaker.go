@@ -0,0 +1,106 @@
+package hlswarm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultBreakerErrorRate is the share of errored segments that fails a cycle
+	DefaultBreakerErrorRate = 0.5
+	// DefaultBreakerMaxBackoff caps how long an open circuit pauses a stream
+	DefaultBreakerMaxBackoff = 5 * time.Minute
+)
+
+// Circuit breaker states of a daemon stream
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// breaker tracks the failed cycles of a daemon stream. After -breaker-failures
+// consecutive failures the circuit opens and the stream is paused; when the pause
+// ends a single probe cycle runs half-open, closing the circuit on success or
+// reopening it with twice the pause on failure.
+type breaker struct {
+	state    string
+	failures int
+	backoff  time.Duration
+}
+
+// breakerProbe marks a stream's circuit half-open when a cycle starts after its
+// pause, so that the cycle's outcome decides whether it closes
+func (h *HLSWarmer) breakerProbe(stream Stream) {
+	if h.breakerFailures <= 0 {
+		return
+	}
+
+	h.streamMu.Lock()
+	b := h.streamBreakers[stream.URL]
+	probing := b != nil && b.state == breakerOpen
+	if probing {
+		b.state = breakerHalfOpen
+	}
+	h.streamMu.Unlock()
+
+	if probing {
+		h.log.Info("Circuit half-open, probing stream", Icon("🟡"), "stream", stream.URL)
+	}
+}
+
+// cycleFailed reports whether a daemon cycle failed: its playlist could not be
+// fetched or parsed, or more than -breaker-error-rate of its segments errored.
+// Rate limiting pauses the stream on its own, and a cycle cut short by a shutdown
+// or by removing the stream (ctx done) is not a failure, so counted is false for
+// them and the cycle should be ignored.
+func (h *HLSWarmer) cycleFailed(ctx context.Context, result *WarmResult, err error) (failed, counted bool) {
+	var rateLimited *rateLimitError
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.As(err, &rateLimited) {
+		return false, false
+	}
+	failed = err != nil
+	if result != nil && result.TotalFiles > 0 {
+		failed = failed || float64(len(result.Errors))/float64(result.TotalFiles) > h.breakerErrorRate
+	}
+	return failed, true
+}
+
+// breakerRecord feeds a cycle's outcome to a stream's circuit breaker
+func (h *HLSWarmer) breakerRecord(ctx context.Context, stream Stream, result *WarmResult, err error) {
+	if h.breakerFailures <= 0 {
+		return
+	}
+	failed, counted := h.cycleFailed(ctx, result, err)
+	if !counted {
+		return
+	}
+
+	h.streamMu.Lock()
+	b := h.streamBreakers[stream.URL]
+	if b == nil {
+		b = &breaker{state: breakerClosed}
+		h.streamBreakers[stream.URL] = b
+	}
+	previous := b.state
+	if !failed {
+		b.state, b.failures, b.backoff = breakerClosed, 0, 0
+	} else {
+		b.failures++
+		if b.state == breakerHalfOpen || b.failures >= h.breakerFailures {
+			b.state = breakerOpen
+			b.backoff = min(max(2*b.backoff, 2*stream.Interval), h.breakerMaxBackoff)
+		}
+	}
+	state, failures, backoff := b.state, b.failures, b.backoff
+	h.streamMu.Unlock()
+
+	switch {
+	case state == breakerOpen:
+		h.pauseStream(stream.URL, backoff)
+		h.log.Warn("Circuit open, backing off stream", Icon("🔴"), "stream", stream.URL, "failures", failures, "backoff", backoff)
+	case state == breakerClosed && previous != breakerClosed:
+		h.log.Info("Circuit closed, resuming normal polling", Icon("🟢"), "stream", stream.URL)
+	}
+}
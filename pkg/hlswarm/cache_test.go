@@ -0,0 +1,100 @@
+package hlswarm
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetectCacheHit(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		headers http.Header
+		want    bool
+	}{
+		{"no cache headers", Config{}, http.Header{}, false},
+		{"X-Cache hit", Config{}, http.Header{"X-Cache": {"HIT"}}, true},
+		{"X-Cache miss", Config{}, http.Header{"X-Cache": {"MISS"}}, false},
+		{"X-Cache shield hit", Config{}, http.Header{"X-Cache": {"MISS, HIT"}}, true},
+		{"Cloudflare hit", Config{}, http.Header{"Cf-Cache-Status": {"HIT"}}, true},
+		{"Cloudflare dynamic", Config{}, http.Header{"Cf-Cache-Status": {"DYNAMIC"}}, false},
+		{"nginx cached", Config{}, http.Header{"X-Cache-Status": {"cached"}}, true},
+		{"Fastly hit", Config{}, http.Header{"X-Fastly-Cache": {"hit"}}, true},
+		{"Varnish miss", Config{}, http.Header{"X-Varnish-Cache": {"MISS"}}, false},
+		{"non-zero Age", Config{}, http.Header{"Age": {"120"}}, true},
+		{"zero Age", Config{}, http.Header{"Age": {"0"}}, false},
+		{"Age fallback disabled", Config{DisableAgeFallback: true}, http.Header{"Age": {"120"}}, false},
+		{"custom header hit", Config{CacheHeader: "X-Edge-Result", CacheHitValue: "served"},
+			http.Header{"X-Edge-Result": {"Served-From-Edge"}}, true},
+		{"custom header overrides heuristics", Config{CacheHeader: "X-Edge-Result", CacheHitValue: "served"},
+			http.Header{"X-Edge-Result": {"origin"}, "X-Cache": {"HIT"}}, false},
+		{"custom header absent ignores heuristics", Config{CacheHeader: "X-Edge-Result"},
+			http.Header{"X-Cache": {"HIT"}}, false},
+		{"custom header absent keeps Age fallback", Config{CacheHeader: "X-Edge-Result"},
+			http.Header{"Age": {"30"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.config)
+			if got := h.detectCacheHit(&http.Response{Header: tt.headers}); got != tt.want {
+				t.Errorf("detectCacheHit(%v) = %v, want %v", tt.headers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarmSegmentsCacheStatus(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/hit.ts":  "segment",
+		"/miss.ts": "segment",
+	}, map[string]http.Header{
+		"/hit.ts":  {"X-Cache": {"HIT"}},
+		"/miss.ts": {"X-Cache": {"MISS"}},
+	})
+	h := newTestWarmer(srv, Config{})
+
+	result, err := h.WarmSegments(context.Background(), []string{srv.URL + "/hit.ts", srv.URL + "/miss.ts"})
+	if err != nil {
+		t.Fatalf("WarmSegments: %v", err)
+	}
+	if result.TotalFiles != 2 || result.CachedFiles != 1 {
+		t.Fatalf("got %d files, %d cached; want 2 files, 1 cached", result.TotalFiles, result.CachedFiles)
+	}
+	for _, status := range result.Details {
+		if wantHit := status.URL == srv.URL+"/hit.ts"; status.Hit != wantHit {
+			t.Errorf("%s: hit = %v, want %v", status.URL, status.Hit, wantHit)
+		}
+	}
+}
+
+func TestWarmSegmentsCountsCompressedBytes(t *testing.T) {
+	body := strings.Repeat("segment ", 1000)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Write([]byte(body))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(body))
+		gz.Close()
+	}))
+	t.Cleanup(srv.Close)
+	h := newTestWarmer(srv, Config{})
+
+	result, err := h.WarmSegments(context.Background(), []string{srv.URL + "/seg1.ts"})
+	if err != nil {
+		t.Fatalf("WarmSegments: %v", err)
+	}
+	status := result.Details[0]
+	if status.Size != int64(len(body)) {
+		t.Errorf("Size = %d, want the decoded %d bytes", status.Size, len(body))
+	}
+	if status.WireBytes <= 0 || status.WireBytes >= status.Size {
+		t.Errorf("WireBytes = %d, want the compressed size, below %d", status.WireBytes, status.Size)
+	}
+}
@@ -0,0 +1,123 @@
+package hlswarm
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultCompareRangeBytes is the Range length of the ranged GET in
+// -compare-methods mode when -prefetch-bytes is not set
+const defaultCompareRangeBytes = 1024
+
+// MethodComparison holds the results of requesting one segment with each
+// compared method, in the order they were issued
+type MethodComparison struct {
+	URL     string
+	Results []MethodResult
+}
+
+// MethodResult is the outcome of one compared request; Label names the request
+// kind ("HEAD", "GET+Range" or "GET"), while Status.Method is the method sent
+type MethodResult struct {
+	Label  string
+	Status CacheStatus
+}
+
+// requestMode overrides the method and prefetch length of segment requests
+type requestMode struct {
+	method        string
+	prefetchBytes int64
+}
+
+// requestModeKey is the context key carrying a requestMode
+type requestModeKey struct{}
+
+// withRequestMode returns a context whose segment requests use the given mode
+func withRequestMode(ctx context.Context, mode requestMode) context.Context {
+	return context.WithValue(ctx, requestModeKey{}, mode)
+}
+
+// requestModeFor returns the mode set by withRequestMode, or the configured
+// -method and -prefetch-bytes
+func (h *HLSWarmer) requestModeFor(ctx context.Context) requestMode {
+	if mode, ok := ctx.Value(requestModeKey{}).(requestMode); ok {
+		return mode
+	}
+	return requestMode{method: h.method, prefetchBytes: h.prefetchBytes}
+}
+
+// compareMethods requests a sample of -compare-methods media segments with HEAD,
+// a ranged GET and a plain GET, one after the other. It runs before warming so
+// the sampled segments are still cold: each request shows whether the ones
+// before it filled the edge's cache.
+func (h *HLSWarmer) compareMethods(ctx context.Context, stream Stream, segments []Segment) []MethodComparison {
+	var media []Segment
+	for _, segment := range segments {
+		if !segment.IsInit && !segment.IsKey {
+			media = append(media, segment)
+		}
+	}
+	sample := sampleSegments(media, h.compareSamples)
+	if len(sample) == 0 {
+		return nil
+	}
+
+	rangeBytes := int64(defaultCompareRangeBytes)
+	if h.prefetchBytes > 0 {
+		rangeBytes = h.prefetchBytes
+	}
+	modes := []struct {
+		label string
+		mode  requestMode
+	}{
+		{"HEAD", requestMode{method: http.MethodHead}},
+		{"GET+Range", requestMode{method: http.MethodGet, prefetchBytes: rangeBytes}},
+		{"GET", requestMode{method: http.MethodGet}},
+	}
+
+	h.log.Info("Comparing request methods", Icon("🔬"), "stream", stream.URL, "segments", len(sample))
+	comparisons := make([]MethodComparison, 0, len(sample))
+	for _, segment := range sample {
+		comparison := MethodComparison{URL: segment.URL}
+		for _, m := range modes {
+			status := h.fetchSegment(withRequestMode(ctx, m.mode), stream, segment)
+			comparison.Results = append(comparison.Results, MethodResult{Label: m.label, Status: status})
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons
+}
+
+// sampleSegments picks n segments spread evenly across segments, keeping their order
+func sampleSegments(segments []Segment, n int) []Segment {
+	if n <= 0 || len(segments) <= n {
+		return segments
+	}
+	sample := make([]Segment, n)
+	for i := range sample {
+		sample[i] = segments[i*len(segments)/n]
+	}
+	return sample
+}
+
+// describeMethodResult formats a compared request as e.g. "206 HIT" or "ERROR"
+func describeMethodResult(r MethodResult) string {
+	var b strings.Builder
+	switch {
+	case r.Status.Error != nil:
+		b.WriteString("ERROR")
+	case r.Status.Hit:
+		b.WriteString(strconv.Itoa(r.Status.StatusCode) + " HIT")
+	default:
+		b.WriteString(strconv.Itoa(r.Status.StatusCode) + " MISS")
+	}
+	if r.Status.Method != "" && !strings.HasPrefix(r.Label, r.Status.Method) {
+		b.WriteString(" (as " + r.Status.Method + ")")
+	}
+	return b.String()
+}
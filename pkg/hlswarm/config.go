@@ -0,0 +1,438 @@
+package hlswarm
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// HTTP Client configuration
+	DefaultRequestTimeout      = 30 * time.Second
+	DefaultConnectTimeout      = 10 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+
+	// User Agent
+	defaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/142.0.0.0 Safari/537.36"
+
+	// Default configuration values
+	DefaultWorkers = 10
+
+	// Cache detection
+	DefaultCacheHitValue = "hit"
+	DefaultInterval      = 1 * time.Second
+	DefaultTTL           = 5 * time.Minute
+
+	// Bounds for the interval derived from EXT-X-TARGETDURATION
+	minAutoInterval = 1 * time.Second
+	maxAutoInterval = 30 * time.Second
+
+	// Retry configuration; retries are opt-in, so by default a failing segment is
+	// requested once
+	DefaultMaxRetries     = 0
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultMaxRetryAfter  = 30 * time.Second
+
+	// Redirects followed per request before it fails
+	DefaultMaxRedirects = 10
+
+	// Delay before warmed segments are re-requested in -verify mode
+	DefaultVerifyDelay = 10 * time.Second
+
+	// Delay between re-requests of a missed segment in warm-until-hit mode
+	DefaultWarmUntilHitDelay = 1 * time.Second
+)
+
+// -ip-version values, and the edge labels used for each family in both mode
+const (
+	IPVersion4    = "4"
+	IPVersion6    = "6"
+	IPVersionBoth = "both"
+
+	edgeIPv4 = "IPv4"
+	edgeIPv6 = "IPv6"
+)
+
+// Config holds the configuration for HLSWarmer
+type Config struct {
+	Workers int
+	// PerHostWorkers caps concurrent segment requests to any one host across all
+	// streams (0 means no per-host limit)
+	PerHostWorkers int
+	// AdaptiveWorkers resizes each daemon stream's worker pool after every cycle
+	// based on its p95 latency and error rate, between MinWorkers (0 means 1) and
+	// MaxWorkers (0 means 4 times Workers), starting from Workers
+	AdaptiveWorkers bool
+	MinWorkers      int
+	MaxWorkers      int
+	Referer         string
+	Origin          string
+	PlaybackID      string
+	// Interval is the daemon check interval; 0 derives it from each playlist's target duration
+	Interval   time.Duration
+	TTL        time.Duration
+	RewarmLast int
+	// MaxRetries is the number of additional attempts for transient failures (0 disables retries)
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	MaxRetryAfter  time.Duration
+	// BreakerFailures opens a daemon stream's circuit after this many consecutive
+	// failed cycles, pausing it for exponentially longer up to BreakerMaxBackoff (0
+	// disables the breaker). A cycle fails when its playlist cannot be fetched or
+	// more than BreakerErrorRate of its segments error.
+	BreakerFailures   int
+	BreakerErrorRate  float64
+	BreakerMaxBackoff time.Duration
+	// WebhookURL receives a JSON POST when a daemon cycle fails, as counted by the
+	// circuit breaker, or its hit ratio is below WebhookMinHitRatio (0 notifies only
+	// failures). Each stream is notified at most once per WebhookInterval.
+	WebhookURL         string
+	WebhookMinHitRatio float64
+	WebhookInterval    time.Duration
+	// LiveEdge makes the daemon warm only the newest segment of each live media
+	// playlist and time its next poll by that segment's EXTINF duration, when the
+	// next one is due, instead of polling every Interval
+	LiveEdge bool
+	// MinInterval and MaxInterval bound an adaptive daemon polling interval:
+	// with MaxInterval set, cycles that find new live segments shorten a stream's
+	// interval toward MinInterval (default: the stream's interval) and runs of
+	// cycles that find none lengthen it toward MaxInterval (0 keeps it fixed)
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// CheckContinuity reports media segments a playlist lists that return 404 and,
+	// in daemon mode, media sequence numbers a live playlist skips between cycles
+	// without a discontinuity
+	CheckContinuity bool
+	// Strict fails a playlist on its first well-formedness problem, such as an
+	// #EXTINF without a segment URI, instead of logging a warning and warming it
+	Strict bool
+	// StaleCycles reports a daemon stream as stale after this many consecutive
+	// cycles without a segment it had not seen before (0 disables the check)
+	StaleCycles int
+	// WarmUntilHit re-requests a missed segment up to this many times until it is a hit (0 disables)
+	WarmUntilHit      int
+	WarmUntilHitDelay time.Duration
+	// UserAgents are rotated round-robin across requests; the built-in desktop
+	// Chrome User-Agent is used when empty, unless MinimalHeaders is set
+	UserAgents []string
+	// MinimalHeaders drops the browser emulation from requests: the desktop Chrome
+	// User-Agent, Accept-Language, Sec-Fetch-* and Priority headers, the Referer and
+	// Origin auto-detected from the playlist and the generated playback session ID.
+	// Only Accept and the headers configured explicitly are sent.
+	MinimalHeaders bool
+	// BasicAuth holds HTTP Basic credentials sent to the host of each stream's
+	// playlist; segments on other hosts never receive them
+	BasicAuth *url.Userinfo
+	// RedactHeaders names request headers, in addition to Authorization, Cookie and
+	// other well-known credentials, whose values are replaced by *** in debug logs
+	RedactHeaders []string
+	// Headers are sent with every request, after (and overriding) the built-in headers
+	Headers map[string]string
+	// Cookies are sent with every request regardless of host
+	Cookies []*http.Cookie
+	// CookieJar stores domain cookies and any Set-Cookie responses; a fresh jar
+	// is used when nil, so cookies set by a playlist are reused for its segments
+	CookieJar http.CookieJar
+	// Proxy routes all requests through an http, https or socks5 proxy; when nil
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply
+	Proxy *url.URL
+	// CacheHeader names a response header that decides cache hits, replacing the
+	// built-in heuristics; a hit is reported when it contains CacheHitValue
+	CacheHeader   string
+	CacheHitValue string
+	// DisableAgeFallback stops a non-zero Age header from counting as a hit
+	DisableAgeFallback bool
+	// Method is the HTTP method used to warm segments: GET (default) or HEAD
+	Method string
+	// EdgeFirst warms the newest N segments of each playlist before older ones (0 keeps playlist order)
+	EdgeFirst int
+	// SegmentsLimit warms at most the newest N media segments per warm or daemon
+	// cycle; in daemon mode the rest are left for later cycles (0 means unlimited)
+	SegmentsLimit int
+	// Variant picks the master playlist variants to warm: "all" (default), "lowest",
+	// "highest" or a bandwidth, selecting the highest variant not above it
+	Variant string
+	// MediaTypes restricts the EXT-X-MEDIA renditions warmed to these TYPEs, e.g.
+	// AUDIO and SUBTITLES (nil means all)
+	MediaTypes []string
+	// AllowHosts and DenyHosts restrict the hosts segments and variant playlists are
+	// fetched from; patterns are host names or "*.example.com" for subdomains. Deny
+	// wins over allow, and an empty AllowHosts allows every host not denied.
+	AllowHosts []string
+	DenyHosts  []string
+	// EdgeIPs warms every segment against each of these edge IPs, keeping the URL's
+	// Host and TLS server name, instead of the edge DNS resolves to
+	EdgeIPs []string
+	// TLSConfig customizes certificate verification and client certificates;
+	// nil verifies against the system roots
+	TLSConfig *tls.Config
+	// HTTP1Only disables HTTP/2, which is otherwise negotiated over TLS when the server offers it
+	HTTP1Only bool
+	// Transport replaces the built-in transport of every request, e.g. with an
+	// httptest.Server's client transport or a fake in tests. Dialing settings
+	// (ConnectTimeout, TLSConfig, HTTP1Only, proxies, edge IPs and IP versions)
+	// are then up to it, while cookies and redirect limits still apply.
+	Transport http.RoundTripper
+	// IPVersion restricts connections to IPv4 ("4") or IPv6 ("6"), or warms every
+	// segment over both and reports each family like an edge ("both")
+	IPVersion string
+	// Verify re-requests every warmed segment VerifyDelay after a one-shot warm and
+	// reports which ones are still cache hits
+	Verify      bool
+	VerifyDelay time.Duration
+	// MaxDuration bounds a whole one-shot warm; when it passes, outstanding workers
+	// stop and the partial result is returned (0 means unlimited)
+	MaxDuration time.Duration
+	// PrefetchBytes requests only the first this many bytes of each segment with a
+	// Range header, which is enough to trigger a cache fill on many CDNs (0 fetches whole segments)
+	PrefetchBytes int64
+	// AcceptEncoding is sent as the Accept-Encoding of segment requests, e.g.
+	// "identity" or "br" (default "gzip")
+	AcceptEncoding string
+	// MaxRedirects is how many redirects a request follows before failing (0 means 10)
+	MaxRedirects int
+	// MaxBodyBytes aborts a segment download once its body exceeds this many bytes (0 means unlimited)
+	MaxBodyBytes int64
+	// WarmFrom and WarmTo limit one-shot warming to the media segments overlapping
+	// this window of playback time, based on EXTINF durations (zero WarmTo means the end)
+	WarmFrom time.Duration
+	WarmTo   time.Duration
+	// Jitter randomizes each daemon polling interval by up to ±this fraction and
+	// staggers each stream's first warm by up to this fraction of its interval
+	Jitter float64
+	// PredictAhead speculatively warms this many numbered segments past the live
+	// edge of each media playlist in daemon mode (0 disables)
+	PredictAhead int
+	// CheckContentType flags segment responses whose Content-Type is not a media type
+	CheckContentType bool
+	// SuccessCriteria decides which segments count as successful in
+	// WarmResult.Succeeded: SuccessCacheHit (default), SuccessStatus2xx or SuccessBoth
+	SuccessCriteria string
+	// Purge sends a PurgeMethod request with PurgeHeaders for every segment of a
+	// one-shot warm before warming it, so the warm fetches fresh content
+	Purge        bool
+	PurgeMethod  string
+	PurgeHeaders map[string]string
+	// CompareMethods requests this many sampled media segments of each one-shot
+	// warm with HEAD, a ranged GET and a plain GET before warming, reporting the
+	// status and cache hit of each side by side (0 disables)
+	CompareMethods int
+	// MinSegmentBytes flags media segment responses smaller than this many bytes (0 disables)
+	MinSegmentBytes int64
+	// DedupIgnoreQuery lists query parameters ignored when deciding whether a segment
+	// was already processed; "*" ignores the whole query string
+	DedupIgnoreQuery []string
+	// MaxTrackedSegments caps how many processed segments the daemon remembers,
+	// evicting the least recently processed first (0 means only TTL expiry)
+	MaxTrackedSegments int
+	// KeepAlive keeps the daemon running after every stream has ended, so that
+	// streams added later (e.g. by a config reload) are still warmed
+	KeepAlive bool
+	// APIAddr serves the daemon's HTTP control API when set
+	APIAddr string
+	// StateFile persists processed segments across daemon restarts when set
+	StateFile string
+	// ConnectTimeout bounds dialing and the TLS handshake; RequestTimeout bounds a whole
+	// request including its body, so slow but progressing downloads are not cut short
+	// by a stuck connect and vice versa
+	ConnectTimeout time.Duration
+	RequestTimeout time.Duration
+	// Rate caps requests per second across all workers and streams (0 means unlimited)
+	Rate float64
+	// Pace spreads each daemon cycle's requests evenly over the stream's interval
+	// instead of dispatching them in a burst
+	Pace       bool
+	DaemonMode bool
+	// Debug lowers LogLevel to debug
+	Debug bool
+	// LogLevel is the minimum level logged; LogFormat is "text" (default) or "json"
+	LogLevel  slog.Level
+	LogFormat string
+	Quiet     bool
+	// Outputs lists the result formats: "text" (default), "json" and "csv"
+	Outputs []string
+	// CSVReport receives one row per segment when Outputs includes "csv"
+	CSVReport *CSVReport
+	// Sinks receive every result in addition to the sinks for Outputs
+	Sinks []ResultSink
+	// OnSegment, when set, is called with the final status of every segment warmed,
+	// and OnCycle with the result of every daemon cycle that warmed segments. Both
+	// are called from worker goroutines, possibly several at once, so they must be
+	// safe for concurrent use and should return quickly.
+	OnSegment func(CacheStatus)
+	OnCycle   func(*WarmResult)
+	// TracerProvider receives a span per warm cycle and per segment request; no
+	// spans are recorded when nil
+	TracerProvider trace.TracerProvider
+	// MetricsAddr is the listen address for the Prometheus endpoint in daemon mode
+	MetricsAddr string
+	// SegmentKeywords mark playlist lines as segments when their URL has no "."
+	// (nil means "seg" and "chunk"); NoSegmentFilter trusts every non-comment line
+	SegmentKeywords []string
+	NoSegmentFilter bool
+	// BaseURL resolves relative references in playlists read from local files
+	// (file:// URLs), which have no HTTP origin of their own
+	BaseURL string
+	// Mirrors spreads the segment requests of streams without mirrors of their own
+	// across these origin hosts, see Stream.Mirrors
+	Mirrors []Mirror
+	// Streams holds per-stream settings, typically loaded from a config file
+	Streams []Stream
+}
+
+// Stream holds the settings for a single stream. Zero values fall back to the
+// warmer-wide configuration.
+type Stream struct {
+	URL        string
+	Referer    string
+	Origin     string
+	Interval   time.Duration
+	TTL        time.Duration
+	RewarmLast int
+	// AutoInterval is set when no interval was configured, so the daemon follows
+	// the playlist's target duration instead
+	AutoInterval bool
+	// Mirrors are origin hosts mirroring the stream's content. Segment requests are
+	// spread across them by weight, replacing each segment URL's host while keeping
+	// its scheme, path and query.
+	Mirrors []Mirror
+	// PlaylistRequest and SegmentRequest send the stream's playlist and segment
+	// requests with a method and body from a template instead of a plain GET
+	PlaylistRequest *RequestTemplate
+	SegmentRequest  *RequestTemplate
+}
+
+// equal reports whether two streams have the same settings
+func (s Stream) equal(other Stream) bool {
+	sameMirrors := slices.Equal(s.Mirrors, other.Mirrors)
+	s.Mirrors, other.Mirrors = nil, nil
+	return sameMirrors && reflect.DeepEqual(s, other)
+}
+
+// CacheStatus represents the status of a segment request
+type CacheStatus struct {
+	URL       string
+	Method    string
+	ByteRange *ByteRange
+	IsKey     bool
+	IsInit    bool
+	// Discontinuity is the discontinuity sequence number of the segment
+	Discontinuity int64
+	// Edge is the edge IP the request was sent to, or the address family in
+	// -ip-version both mode; empty when DNS picked the edge
+	Edge string
+	// Mirror is the mirror host the request was sent to, empty without mirrors
+	Mirror     string
+	Hit        bool
+	StatusCode int
+	Headers    map[string]string
+	Error      error
+	Duration   time.Duration
+	Attempts   int
+	RetryAfter time.Duration
+	// Time is when the first request for the segment started
+	Time time.Time
+	// Timing breaks down where the last request's time went
+	Timing Timing
+	// MediaDuration is the playback duration of the segment from EXTINF, zero for
+	// keys, init segments and segments listed without one
+	MediaDuration time.Duration
+	// Redirects is the chain of URLs the request was redirected through, starting
+	// with URL and ending with the URL that served the response; nil without redirects
+	Redirects []string
+	// Partial is set when only the first -prefetch-bytes of the segment were
+	// requested and the origin answered with 206 Partial Content
+	Partial bool
+	// UserAgent is the User-Agent the response was requested with
+	UserAgent string
+	// Proto is the protocol the response was served over, e.g. "HTTP/2.0"
+	Proto string
+	// ContentType and Size describe the response body. Size is the decoded body
+	// size and WireBytes what was transferred, which is smaller for gzip bodies;
+	// both are the Content-Length for HEAD.
+	ContentType string
+	Size        int64
+	WireBytes   int64
+	// Suspicious explains why a successful response looks like it is not a real
+	// segment, e.g. an error page cached with status 200; empty when it looks fine
+	Suspicious string
+}
+
+// Timing holds the phases of a request. DNS, Connect and TLS are zero when a
+// pooled connection was reused; TTFB runs from the start of the request to the
+// first response byte and Transfer from there to the end of the body. Connected
+// is set once the request got a connection, and Reused when that was an idle
+// keep-alive connection rather than a new one.
+type Timing struct {
+	DNS       time.Duration
+	Connect   time.Duration
+	TLS       time.Duration
+	TTFB      time.Duration
+	Transfer  time.Duration
+	Connected bool
+	Reused    bool
+}
+
+// WarmResult represents the result of warming an M3U8 playlist
+type WarmResult struct {
+	M3U8URL     string
+	TotalFiles  int
+	CachedFiles int
+	// Succeeded counts the segments that succeeded under SuccessCriteria
+	Succeeded       int
+	SuccessCriteria string
+	// SuspiciousFiles counts responses flagged as suspicious
+	SuspiciousFiles int
+	// Predicted and PredictedNotFound count speculative requests for segments
+	// extrapolated past the live edge that existed and that did not
+	Predicted         int
+	PredictedNotFound int
+	// Duplicates counts segments shared by several variants that were warmed only once
+	Duplicates int
+	// Disallowed counts segments and variant playlists skipped because of their host
+	Disallowed int
+	// Deferred counts media segments left unwarmed by SegmentsLimit
+	Deferred int
+	// StatusCodes counts the segment responses by HTTP status; requests that got
+	// no response at all are not counted
+	StatusCodes map[int]int
+	// TotalBytes and WireBytes sum the decoded and transferred sizes of all bodies
+	TotalBytes int64
+	WireBytes  int64
+	// TotalDuration sums the playback duration of the segments warmed successfully,
+	// while Duration is the wall-clock time warming took
+	TotalDuration time.Duration
+	Errors        []error
+	Duration      time.Duration
+	Details       []CacheStatus
+	Variants      []Variant
+	// Verification holds the re-request results in -verify mode, nil otherwise
+	Verification *Verification
+	// Continuity holds the -check-continuity findings, nil when the check is off
+	Continuity *Continuity
+	// ParseWarnings are the well-formedness problems found in the playlists
+	ParseWarnings []ParseWarning
+	// Purge holds the purge results in -purge mode, nil otherwise
+	Purge *Purge
+	// MethodComparison holds the sampled per-method results in -compare-methods mode
+	MethodComparison []MethodComparison
+	// DeadlineExceeded reports that MaxDuration cut the warm short, leaving
+	// Unwarmed segment requests that were never made
+	DeadlineExceeded bool
+	Unwarmed         int
+	// P50, P95 and P99 are percentiles of the segment request durations
+	P50, P95, P99 time.Duration
+	// ReusedConns and NewConns count the segment requests sent over a reused
+	// keep-alive connection and over a newly opened one
+	ReusedConns int
+	NewConns    int
+}
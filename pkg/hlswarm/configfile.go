@@ -0,0 +1,184 @@
+package hlswarm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the configuration loaded with -config. Top-level values apply to
+// every stream; each entry in Streams may override them for that stream.
+type FileConfig struct {
+	Workers    int          `yaml:"workers" json:"workers"`
+	Referer    string       `yaml:"referer" json:"referer"`
+	Origin     string       `yaml:"origin" json:"origin"`
+	Interval   fileDuration `yaml:"interval" json:"interval"`
+	TTL        fileDuration `yaml:"ttl" json:"ttl"`
+	RewarmLast int          `yaml:"rewarm_last" json:"rewarm_last"`
+	Streams    []FileStream `yaml:"streams" json:"streams"`
+}
+
+// FileStream is a single stream entry in a config file
+type FileStream struct {
+	URL        string       `yaml:"url" json:"url"`
+	Referer    string       `yaml:"referer" json:"referer"`
+	Origin     string       `yaml:"origin" json:"origin"`
+	Interval   fileDuration `yaml:"interval" json:"interval"`
+	TTL        fileDuration `yaml:"ttl" json:"ttl"`
+	RewarmLast int          `yaml:"rewarm_last" json:"rewarm_last"`
+	Mirrors    []Mirror     `yaml:"mirrors" json:"mirrors"`
+	// PlaylistRequest and SegmentRequest send requests with a templated body
+	PlaylistRequest *RequestTemplate `yaml:"playlist_request" json:"playlist_request"`
+	SegmentRequest  *RequestTemplate `yaml:"segment_request" json:"segment_request"`
+}
+
+// fileDuration is a time.Duration written as a Go duration string (e.g. "10s")
+type fileDuration time.Duration
+
+func (d *fileDuration) UnmarshalYAML(value *yaml.Node) error {
+	return d.parse(value.Value)
+}
+
+func (d *fileDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\": %v", err)
+	}
+	return d.parse(s)
+}
+
+func (d *fileDuration) parse(s string) error {
+	parsed, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("duration %q must not be negative", s)
+	}
+	*d = fileDuration(parsed)
+	return nil
+}
+
+// LoadConfigFile reads a YAML or JSON config file; the format is chosen by extension
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config FileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	default:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return &config, nil
+}
+
+// validate checks the config file for missing or duplicate stream URLs
+func (c *FileConfig) validate() error {
+	if c.Workers < 0 {
+		return fmt.Errorf("workers must not be negative")
+	}
+
+	seen := make(map[string]bool)
+	for i, stream := range c.Streams {
+		if stream.URL == "" {
+			return fmt.Errorf("stream %d: url is required", i+1)
+		}
+		if seen[stream.URL] {
+			return fmt.Errorf("stream %d: duplicate url %s", i+1, stream.URL)
+		}
+		if stream.RewarmLast < 0 {
+			return fmt.Errorf("stream %d: rewarm_last must not be negative", i+1)
+		}
+		for _, mirror := range stream.Mirrors {
+			if err := mirror.validate(); err != nil {
+				return fmt.Errorf("stream %d: %v", i+1, err)
+			}
+		}
+		if err := stream.PlaylistRequest.validate(); err != nil {
+			return fmt.Errorf("stream %d: playlist_request: %v", i+1, err)
+		}
+		if err := stream.SegmentRequest.validate(); err != nil {
+			return fmt.Errorf("stream %d: segment_request: %v", i+1, err)
+		}
+		seen[stream.URL] = true
+	}
+
+	return nil
+}
+
+// Apply merges the file into config. Flags named in overridden were set on the
+// command line and take precedence over both top-level and per-stream values.
+func (c *FileConfig) Apply(config *Config, overridden map[string]bool) {
+	if c.Workers != 0 && !overridden["workers"] {
+		config.Workers = c.Workers
+	}
+	if c.Referer != "" && !overridden["referer"] {
+		config.Referer = c.Referer
+	}
+	if c.Origin != "" && !overridden["origin"] {
+		config.Origin = c.Origin
+	}
+	if c.Interval != 0 && !overridden["interval"] {
+		config.Interval = time.Duration(c.Interval)
+	}
+	if c.TTL != 0 && !overridden["ttl"] {
+		config.TTL = time.Duration(c.TTL)
+	}
+	if c.RewarmLast != 0 && !overridden["rewarm-last"] {
+		config.RewarmLast = c.RewarmLast
+	}
+
+	for _, fs := range c.Streams {
+		stream := Stream{URL: fs.URL, PlaylistRequest: fs.PlaylistRequest, SegmentRequest: fs.SegmentRequest}
+		if !overridden["referer"] {
+			stream.Referer = fs.Referer
+		}
+		if !overridden["origin"] {
+			stream.Origin = fs.Origin
+		}
+		if !overridden["interval"] {
+			stream.Interval = time.Duration(fs.Interval)
+		}
+		if !overridden["ttl"] {
+			stream.TTL = time.Duration(fs.TTL)
+		}
+		if !overridden["rewarm-last"] {
+			stream.RewarmLast = fs.RewarmLast
+		}
+		if !overridden["mirror"] {
+			stream.Mirrors = fs.Mirrors
+		}
+		config.Streams = append(config.Streams, stream)
+	}
+}
+
+// URLs returns the stream URLs in file order
+func (c *FileConfig) URLs() []string {
+	urls := make([]string, 0, len(c.Streams))
+	for _, stream := range c.Streams {
+		urls = append(urls, stream.URL)
+	}
+	return urls
+}
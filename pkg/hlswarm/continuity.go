@@ -0,0 +1,108 @@
+package hlswarm
+
+import "net/http"
+
+// SequenceGap is a run of media sequence numbers that a live media playlist
+// skipped between two daemon cycles without signalling a discontinuity
+type SequenceGap struct {
+	// Playlist is the URL of the media playlist
+	Playlist string
+	// From and To are the first and last missing media sequence numbers
+	From int64
+	To   int64
+}
+
+// Continuity holds what -check-continuity found in a warm
+type Continuity struct {
+	// Gaps are the sequence numbers skipped since the previous daemon cycle;
+	// always empty for one-shot warms
+	Gaps []SequenceGap
+	// Missing are the URLs of the media segments listed in a playlist that
+	// returned 404, which would stall playback
+	Missing []string
+}
+
+// sequenceMark is the newest media segment seen in a media playlist
+type sequenceMark struct {
+	sequence      int64
+	discontinuity int64
+}
+
+// checkSequences compares each media playlist's media sequence numbers with the
+// newest one seen in the stream's previous cycle and returns the numbers skipped
+// in between. A jump that comes with a higher discontinuity sequence, such as an
+// encoder restart, is expected and not reported; a sequence going backwards
+// without one is logged.
+func (h *HLSWarmer) checkSequences(m3u8URL string, segments []Segment) []SequenceGap {
+	if !h.checkContinuity {
+		return nil
+	}
+
+	first := make(map[string]Segment)
+	last := make(map[string]Segment)
+	var playlists []string
+	for _, segment := range segments {
+		if !segment.HasSequence || segment.IsKey || segment.IsInit {
+			continue
+		}
+		if f, ok := first[segment.Playlist]; !ok || segment.SequenceNumber < f.SequenceNumber {
+			if !ok {
+				playlists = append(playlists, segment.Playlist)
+			}
+			first[segment.Playlist] = segment
+		}
+		if l, ok := last[segment.Playlist]; !ok || segment.SequenceNumber > l.SequenceNumber {
+			last[segment.Playlist] = segment
+		}
+	}
+
+	var gaps []SequenceGap
+	h.streamMu.Lock()
+	marks := h.streamSequences[m3u8URL]
+	if marks == nil {
+		marks = make(map[string]sequenceMark)
+		h.streamSequences[m3u8URL] = marks
+	}
+	for _, playlist := range playlists {
+		oldest, newest := first[playlist], last[playlist]
+		mark, seen := marks[playlist]
+		restarted := seen && oldest.Discontinuity > mark.discontinuity
+		switch {
+		case !seen || restarted:
+		case oldest.SequenceNumber > mark.sequence+1:
+			gaps = append(gaps, SequenceGap{Playlist: playlist, From: mark.sequence + 1, To: oldest.SequenceNumber - 1})
+		case newest.SequenceNumber < mark.sequence:
+			h.log.Warn("Media sequence went backwards without a discontinuity", Icon("⏪"), "stream", m3u8URL,
+				"playlist", playlist, "sequence", newest.SequenceNumber, "previous", mark.sequence)
+		}
+		marks[playlist] = sequenceMark{sequence: newest.SequenceNumber, discontinuity: newest.Discontinuity}
+	}
+	h.streamMu.Unlock()
+
+	for _, gap := range gaps {
+		h.log.Warn("Media sequence gap", Icon("🕳️"), "stream", m3u8URL, "playlist", gap.Playlist,
+			"from", gap.From, "to", gap.To, "missing", gap.To-gap.From+1)
+	}
+	h.metrics.observeContinuity(m3u8URL, len(gaps), 0)
+	return gaps
+}
+
+// continuityOf builds the -check-continuity report of a warm from its sequence
+// gaps and segment results, or returns nil when the check is off
+func (h *HLSWarmer) continuityOf(m3u8URL string, gaps []SequenceGap, results []CacheStatus) *Continuity {
+	if !h.checkContinuity {
+		return nil
+	}
+
+	continuity := &Continuity{Gaps: gaps}
+	for _, r := range results {
+		if r.StatusCode == http.StatusNotFound && !r.IsKey && !r.IsInit {
+			continuity.Missing = append(continuity.Missing, r.URL)
+		}
+	}
+	if len(continuity.Missing) > 0 {
+		h.log.Warn("Playlist references missing segments", Icon("🕳️"), "stream", m3u8URL, "count", len(continuity.Missing))
+		h.metrics.observeContinuity(m3u8URL, 0, len(continuity.Missing))
+	}
+	return continuity
+}
@@ -0,0 +1,102 @@
+package hlswarm
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// csvHeader lists the columns of a CSV report
+var csvHeader = []string{"timestamp", "stream_url", "segment_url", "status_code", "result", "duration_ms", "bytes", "error"}
+
+// CSVReport writes one row per warmed segment to a CSV file. Rows from
+// concurrent streams and daemon cycles are appended under a lock.
+type CSVReport struct {
+	mu     sync.Mutex
+	w      *csv.Writer
+	closer io.Closer
+}
+
+// OpenCSVReport opens a CSV report at path for appending, writing the header
+// row only when the file is new or empty. An empty path writes to stdout.
+func OpenCSVReport(path string) (*CSVReport, error) {
+	if path == "" {
+		report := &CSVReport{w: csv.NewWriter(os.Stdout)}
+		return report, report.writeRow(csvHeader)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	report := &CSVReport{w: csv.NewWriter(file), closer: file}
+	if info.Size() == 0 {
+		if err := report.writeRow(csvHeader); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// write appends a row for every segment of a result
+func (r *CSVReport) write(result *WarmResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, detail := range result.Details {
+		outcome := "miss"
+		errText := ""
+		switch {
+		case detail.Error != nil:
+			outcome = "error"
+			errText = detail.Error.Error()
+		case detail.Hit:
+			outcome = "hit"
+		}
+
+		r.w.Write([]string{
+			detail.Time.UTC().Format(time.RFC3339Nano),
+			result.M3U8URL,
+			detail.URL,
+			strconv.Itoa(detail.StatusCode),
+			outcome,
+			strconv.FormatFloat(milliseconds(detail.Duration), 'f', 3, 64),
+			strconv.FormatInt(max(detail.WireBytes, 0), 10),
+			errText,
+		})
+	}
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// writeRow writes a single row and flushes it
+func (r *CSVReport) writeRow(row []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.w.Write(row)
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// toStdout reports whether the report is written to stdout
+func (r *CSVReport) toStdout() bool {
+	return r != nil && r.closer == nil
+}
+
+// Close closes the report file
+func (r *CSVReport) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
@@ -0,0 +1,591 @@
+package hlswarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RunDaemon runs the warmer in daemon mode, continuously warming M3U8 streams
+func (h *HLSWarmer) RunDaemon(ctx context.Context, m3u8URLs []string) error {
+	startTime := time.Now()
+	h.log.Info("Starting daemon mode", Icon("🔄"), "streams", len(m3u8URLs))
+	if h.autoInterval {
+		h.log.Info("Check interval: auto from target duration", Icon("⏱️"), "interval", h.interval)
+	} else {
+		h.log.Info("Check interval", Icon("⏱️"), "interval", h.interval)
+	}
+
+	// Resume from the previous run so restarts do not re-warm every known segment
+	if h.stateFile != "" {
+		if err := h.loadState(); err != nil {
+			return err
+		}
+
+		stateCtx, stopState := context.WithCancel(ctx)
+		stateDone := make(chan struct{})
+		go func() {
+			defer close(stateDone)
+			h.persistState(stateCtx)
+		}()
+		defer func() {
+			stopState()
+			<-stateDone
+		}()
+	}
+
+	if h.metrics != nil {
+		h.log.Info("Serving metrics", Icon("📈"), "url", "http://"+h.metricsAddr+"/metrics")
+		go h.metrics.serve(ctx, h.metricsAddr, h.log)
+	}
+
+	// Initial warming
+	for _, m3u8URL := range m3u8URLs {
+		stream := h.streamFor(m3u8URL)
+		if stream.Interval != h.interval {
+			h.log.Info("Check interval", Icon("⏱️"), "stream", m3u8URL, "interval", stream.Interval)
+		}
+		h.startStream(ctx, stream)
+	}
+
+	if h.apiAddr != "" {
+		h.log.Info("Serving control API", Icon("🎛️"), "url", "http://"+h.apiAddr)
+		go h.serveAPI(ctx)
+	}
+
+	// Unless streams can still be added later, stop once every stream has ended
+	var allEnded chan struct{}
+	if !h.keepAlive {
+		allEnded = make(chan struct{})
+		go func() {
+			h.streamWG.Wait()
+			close(allEnded)
+		}()
+	}
+
+	// Wait for context cancellation or for every stream to end
+	select {
+	case <-ctx.Done():
+		// Let in-flight cycles wind down so the summary includes them, and their
+		// webhook notifications go out. The stream loops go first, as they are
+		// what start cycles, and cycles are what queue notifications.
+		h.streamWG.Wait()
+		h.cycleWG.Wait()
+		h.webhookWG.Wait()
+		h.log.Info("Daemon mode stopped", Icon("🛑"))
+		h.PrintDaemonSummary(time.Since(startTime))
+		return ctx.Err()
+	case <-allEnded:
+		// A stream is marked ended before its last cycle has sent its notifications
+		h.cycleWG.Wait()
+		h.webhookWG.Wait()
+		h.log.Info("All streams have ended, daemon mode stopped", Icon("🏁"))
+		h.PrintDaemonSummary(time.Since(startTime))
+		return nil
+	}
+}
+
+// RunCycle warms every stream exactly once the way a daemon cycle does, skipping
+// segments already processed within their TTL, and then returns. With a state file
+// this lets an external scheduler drive the loop while keeping the daemon's
+// new-segment filtering across runs. It returns the outcome of every stream in
+// the order given, once their webhook notifications have been sent.
+func (h *HLSWarmer) RunCycle(ctx context.Context, m3u8URLs []string) ([]CycleOutcome, error) {
+	if h.stateFile != "" {
+		if err := h.loadState(); err != nil {
+			return nil, err
+		}
+	}
+
+	outcomes := make([]CycleOutcome, len(m3u8URLs))
+	var wg sync.WaitGroup
+	for i, m3u8URL := range m3u8URLs {
+		stream := h.streamFor(m3u8URL)
+		wg.Go(func() {
+			result, err := h.warmStreamOnce(ctx, stream)
+			outcomes[i] = CycleOutcome{URL: m3u8URL, Result: result, Err: err}
+		})
+	}
+	wg.Wait()
+	h.webhookWG.Wait()
+
+	if h.stateFile != "" {
+		if err := h.saveState(); err != nil {
+			return outcomes, fmt.Errorf("saving state: %v", err)
+		}
+	}
+	return outcomes, ctx.Err()
+}
+
+// CycleOutcome is the outcome of one stream in a RunCycle call
+type CycleOutcome struct {
+	URL string
+	// Result is nil when the playlist failed or had no new segments
+	Result *WarmResult
+	// Err is set when the playlist could not be fetched or parsed
+	Err error
+}
+
+// streamRun is a stream being warmed continuously by the daemon
+type streamRun struct {
+	cancel context.CancelFunc
+}
+
+// startStream warms a stream continuously in the background until it ends or is
+// removed. It returns false if the stream is already running.
+func (h *HLSWarmer) startStream(ctx context.Context, stream Stream) bool {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+
+	if _, running := h.streamRuns[stream.URL]; running {
+		return false
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	run := &streamRun{cancel: cancel}
+	h.streamRuns[stream.URL] = run
+	delete(h.streamEnded, stream.URL)
+
+	h.streamWG.Add(1)
+	go func() {
+		defer h.streamWG.Done()
+		defer func() {
+			h.streamMu.Lock()
+			if h.streamRuns[stream.URL] == run {
+				delete(h.streamRuns, stream.URL)
+			}
+			h.streamMu.Unlock()
+			cancel()
+		}()
+
+		// Stagger the first warm so streams started together do not poll in step
+		if h.jitter > 0 && !sleepContext(streamCtx, time.Duration(rand.Float64()*h.jitter*float64(stream.Interval))) {
+			return
+		}
+
+		h.warmStreamContinuously(streamCtx, stream, h.scheduleStreamWarm(streamCtx, stream))
+	}()
+	return true
+}
+
+// addStream registers a new stream's settings and starts warming it. It returns
+// false if the stream is already running.
+func (h *HLSWarmer) addStream(ctx context.Context, stream Stream) bool {
+	h.streamMu.Lock()
+	if _, running := h.streamRuns[stream.URL]; running {
+		h.streamMu.Unlock()
+		return false
+	}
+	h.streams[stream.URL] = stream
+	h.streamMu.Unlock()
+
+	return h.startStream(ctx, h.streamFor(stream.URL))
+}
+
+// removeStream stops warming a stream, aborting any in-flight requests. Its
+// statistics are kept, so the daemon summary's lifetime totals still include it.
+// It returns false if the stream is not running.
+func (h *HLSWarmer) removeStream(m3u8URL string) bool {
+	h.streamMu.Lock()
+	run, running := h.streamRuns[m3u8URL]
+	delete(h.streamRuns, m3u8URL)
+	delete(h.streams, m3u8URL)
+	delete(h.streamResults, m3u8URL)
+	delete(h.streamBreakers, m3u8URL)
+	delete(h.streamIdle, m3u8URL)
+	delete(h.streamBackoffs, m3u8URL)
+	delete(h.streamSequences, m3u8URL)
+	delete(h.streamEdges, m3u8URL)
+	delete(h.streamWebhooks, m3u8URL)
+	delete(h.streamMirrors, m3u8URL)
+	h.streamMu.Unlock()
+
+	if running {
+		run.cancel()
+	}
+	return running
+}
+
+// ReloadStreams reconciles running streams with a reloaded stream list: streams
+// only in next are started, streams only in previous are stopped and streams whose
+// settings changed are restarted. Unchanged streams keep running untouched.
+func (h *HLSWarmer) ReloadStreams(ctx context.Context, previous, next []Stream) {
+	old := make(map[string]Stream, len(previous))
+	for _, stream := range previous {
+		old[stream.URL] = stream
+	}
+
+	var added, removed, updated int
+	for _, stream := range next {
+		prev, existed := old[stream.URL]
+		delete(old, stream.URL)
+
+		switch {
+		case !existed:
+			if h.addStream(ctx, stream) {
+				h.log.Info("Stream added", Icon("➕"), "stream", stream.URL)
+				added++
+			}
+		case !prev.equal(stream):
+			h.removeStream(stream.URL)
+			h.addStream(ctx, stream)
+			h.log.Info("Stream updated", Icon("🔁"), "stream", stream.URL)
+			updated++
+		}
+	}
+
+	for m3u8URL := range old {
+		h.removeStream(m3u8URL)
+		h.log.Info("Stream removed", Icon("➖"), "stream", m3u8URL)
+		removed++
+	}
+
+	h.log.Info("Config reloaded", Icon("🔄"), "added", added, "removed", removed, "updated", updated)
+}
+
+// warmStreamContinuously warms a single stream continuously, after its first
+// cycle, which is signalled by first
+func (h *HLSWarmer) warmStreamContinuously(ctx context.Context, stream Stream, first <-chan struct{}) {
+	// With -live-edge the wait depends on what a cycle found, so it starts once
+	// the cycle is done
+	if h.liveEdge && !waitCycle(ctx, first) {
+		return
+	}
+
+	// A timer rather than a ticker, so every wait can be jittered independently
+	timer := time.NewTimer(h.jittered(h.streamInterval(stream)))
+	defer timer.Stop()
+
+	h.metrics.streamStarted()
+	defer h.metrics.streamStopped()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if h.streamHasEnded(stream.URL) {
+				h.log.Info("Stream has ended (EXT-X-ENDLIST), stopping polling", Icon("🏁"), "stream", stream.URL)
+				return
+			}
+
+			// Follow the playlist's target duration when no interval was configured
+			if stream.AutoInterval {
+				if interval := autoInterval(h.streamTargetDuration(stream.URL)); interval > 0 && interval != stream.Interval {
+					h.log.Info("Check interval from target duration", Icon("⏱️"), "stream", stream.URL, "interval", interval)
+					stream.Interval = interval
+				}
+			}
+
+			done := h.scheduleStreamWarm(ctx, stream)
+			if h.liveEdge && !waitCycle(ctx, done) {
+				return
+			}
+			timer.Reset(h.jittered(h.streamInterval(stream)))
+		}
+	}
+}
+
+// waitCycle waits for a cycle started by scheduleStreamWarm to finish, returning
+// false if ctx is cancelled first. A nil done, for a cycle that was not started,
+// returns at once.
+func waitCycle(ctx context.Context, done <-chan struct{}) bool {
+	if done == nil {
+		return true
+	}
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// jittered randomizes an interval by up to ±-jitter of its length, keeping the
+// average interval unchanged
+func (h *HLSWarmer) jittered(interval time.Duration) time.Duration {
+	if h.jitter <= 0 {
+		return interval
+	}
+	return time.Duration(float64(interval) * (1 + h.jitter*(2*rand.Float64()-1)))
+}
+
+// scheduleStreamWarm triggers a warm cycle for the given stream in the background if no other cycle is currently running.
+// It returns a channel closed when the cycle is done, or nil when no cycle was started.
+func (h *HLSWarmer) scheduleStreamWarm(ctx context.Context, stream Stream) <-chan struct{} {
+	m3u8URL := stream.URL
+
+	if remaining := h.streamPausedFor(m3u8URL); remaining > 0 {
+		h.log.Debug("Stream paused, skipping this tick", Icon("⏸️"), "stream", m3u8URL, "remaining", remaining.Round(time.Millisecond))
+		return nil
+	}
+
+	if !h.beginStreamProcessing(m3u8URL) {
+		h.log.Debug("Stream already warming, skipping this tick", Icon("⏳"), "stream", m3u8URL)
+		return nil
+	}
+
+	done := make(chan struct{})
+	h.cycleWG.Add(1)
+	go func() {
+		defer h.cycleWG.Done()
+		defer close(done)
+		defer h.endStreamProcessing(m3u8URL)
+		h.warmStreamOnce(ctx, stream)
+	}()
+	return done
+}
+
+// warmStreamOnce warms a stream once, only processing new segments, within a
+// trace span covering the cycle
+func (h *HLSWarmer) warmStreamOnce(ctx context.Context, stream Stream) (*WarmResult, error) {
+	h.breakerProbe(stream)
+	ctx, span := h.spans.Start(ctx, "warm cycle", trace.WithAttributes(attribute.String("hls.stream", stream.URL)))
+	result, err := h.warmNewSegments(ctx, stream)
+	endCycleSpan(span, result, err)
+	h.breakerRecord(ctx, stream, result, err)
+	h.notifyWebhook(ctx, stream, result, err)
+	if result != nil && h.onCycle != nil {
+		h.onCycle(result)
+	}
+	return result, err
+}
+
+// warmNewSegments warms the segments of a stream not processed yet. It returns
+// the cycle's result, nil when there were no new segments, or an error if the
+// playlist could not be fetched or parsed.
+func (h *HLSWarmer) warmNewSegments(ctx context.Context, stream Stream) (*WarmResult, error) {
+	startTime := time.Now()
+	m3u8URL := stream.URL
+
+	playlist, err := h.parseManifest(ctx, stream)
+	if err != nil {
+		// Shutting down; the failure is just the aborted request
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// Back off the whole stream when the playlist fetch is rate limited
+		var rateLimited *rateLimitError
+		if errors.As(err, &rateLimited) {
+			pause := h.capRetryAfter(rateLimited.retryAfter)
+			if pause == 0 {
+				pause = stream.Interval
+			}
+			h.pauseStream(m3u8URL, pause)
+			h.log.Warn("Rate limited, pausing stream", Icon("⏸️"), "stream", m3u8URL, "pause", pause)
+			return nil, err
+		}
+
+		// Clean error message to prevent terminal corruption
+		h.log.Warn("Error parsing M3U8", "stream", m3u8URL, "error", cleanString(err.Error()))
+		return nil, err
+	}
+	segments := playlist.Segments
+
+	if playlist.TargetDuration > 0 {
+		h.setStreamTargetDuration(m3u8URL, playlist.TargetDuration)
+	}
+
+	gaps := h.checkSequences(m3u8URL, segments)
+
+	// Filter out already processed segments, tracked by sequence number where available
+	var newSegments []Segment
+	fresh := 0
+	h.mu.Lock()
+	for _, segment := range segments {
+		key := h.dedupKey(segment)
+		last, seen := h.processedURLs[key]
+		if !seen || time.Since(last) > stream.TTL {
+			newSegments = append(newSegments, segment)
+			h.processedURLs[key] = time.Now()
+		}
+		if !seen {
+			fresh++
+		}
+	}
+	h.mu.Unlock()
+
+	// Segments only rewarmed or past their TTL do not show that a live playlist
+	// is still moving, so staleness and the adaptive interval count never-seen
+	// segments alone
+	if playlist.Live {
+		h.recordFreshSegments(m3u8URL, fresh)
+		h.adaptInterval(stream, fresh)
+	}
+
+	// Warm only the live edge, timed by its segment durations
+	if h.liveEdge && playlist.Live {
+		newSegments = h.liveEdgeSegments(stream, segments, newSegments)
+	}
+
+	// Optionally include the last N segments for re-warming even if previously
+	// seen; -live-edge exists to avoid exactly that
+	if stream.RewarmLast > 0 && !h.liveEdge {
+		h.mu.Lock()
+		start := 0
+		if len(segments) > stream.RewarmLast {
+			start = len(segments) - stream.RewarmLast
+		}
+		// use a map to avoid duplicates
+		included := make(map[string]struct{})
+		for _, s := range newSegments {
+			included[h.dedupKey(s)] = struct{}{}
+		}
+		for i := start; i < len(segments); i++ {
+			s := segments[i]
+			key := h.dedupKey(s)
+			if _, ok := included[key]; !ok {
+				newSegments = append(newSegments, s)
+				included[key] = struct{}{}
+			}
+			// update processed time so it won't be re-added immediately next cycle
+			h.processedURLs[key] = time.Now()
+		}
+		h.mu.Unlock()
+	}
+
+	// Warm at most -segments-limit segments; the older ones are forgotten again so
+	// that later cycles pick them up
+	var deferred []Segment
+	if h.segmentsLimit > 0 {
+		newSegments, deferred = limitSegments(newSegments, h.segmentsLimit)
+		h.mu.Lock()
+		for _, segment := range deferred {
+			delete(h.processedURLs, h.dedupKey(segment))
+		}
+		h.mu.Unlock()
+		if len(deferred) > 0 {
+			h.log.Info("Segment limit reached, deferring older segments", Icon("⏭️"), "stream", m3u8URL,
+				"limit", h.segmentsLimit, "deferred", len(deferred))
+		}
+	}
+
+	// A finished (VOD) playlist never changes, so stop polling it once nothing is
+	// left for later cycles. It is marked only when this cycle returns: the
+	// polling loop stops on an ended stream, cancelling any cycle still warming.
+	if !playlist.Live && len(deferred) == 0 {
+		defer h.markStreamEnded(m3u8URL)
+	}
+
+	h.mu.Lock()
+	h.evictProcessed()
+	h.mu.Unlock()
+
+	if len(newSegments) == 0 {
+		h.log.Info("No new segments found", Icon("🔍"), "stream", m3u8URL)
+		return nil, nil
+	}
+
+	h.log.Info("Found new segments", Icon("🆕"), "stream", m3u8URL, "segments", len(newSegments))
+
+	// Warm new segments
+	results := h.warmPlaylistSegments(ctx, stream, newSegments)
+	h.metrics.observeResults(m3u8URL, results)
+	h.adaptWorkers(m3u8URL, results)
+
+	// With the live edge warm, get ahead of the playlist. Predictions are kept out
+	// of the cycle's results so they do not skew its hit ratio.
+	var predicted, notFound int
+	if h.predictAhead > 0 && playlist.Live {
+		predicted, notFound = h.warmPredicted(ctx, stream, segments)
+	}
+
+	result := newWarmResult(m3u8URL, results, time.Since(startTime), h.successCriteria)
+	result.Predicted, result.PredictedNotFound = predicted, notFound
+	result.Disallowed = playlist.Disallowed
+	result.Deferred = len(deferred)
+	result.Continuity = h.continuityOf(m3u8URL, gaps, results)
+	result.ParseWarnings = playlist.Warnings
+	h.setStreamResult(m3u8URL, result)
+	h.metrics.observeLatency(result)
+	for _, sink := range h.sinks {
+		sink.RecordCycle(result)
+	}
+
+	stats := h.recordStreamStats(m3u8URL, results)
+	h.metrics.observeHitRatio(m3u8URL, stats.HitRatio())
+	if trend := stats.trend(); trend != "" {
+		h.log.Info("Hit ratio trend", Icon("📈"), "stream", m3u8URL, "trend", trend, "overall", fmt.Sprintf("%.0f%%", stats.HitRatio()*100))
+	}
+	return result, nil
+}
+
+// dedupKey returns the key under which a processed segment is remembered. Query
+// parameters named in -dedup-ignore-query are stripped first, so rotating tokens
+// do not make a known segment look new; requests still use the full URL.
+func (h *HLSWarmer) dedupKey(segment Segment) string {
+	segment.URL = h.stripDedupQuery(segment.URL)
+	segment.Playlist = h.stripDedupQuery(segment.Playlist)
+	return segment.trackingKey()
+}
+
+// stripDedupQuery removes the ignored query parameters from a URL, or the whole
+// query when "*" is ignored
+func (h *HLSWarmer) stripDedupQuery(rawURL string) string {
+	if len(h.dedupIgnoreQuery) == 0 || !strings.Contains(rawURL, "?") {
+		return rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	if slices.Contains(h.dedupIgnoreQuery, "*") {
+		parsedURL.RawQuery = ""
+	} else {
+		query := parsedURL.Query()
+		for _, name := range h.dedupIgnoreQuery {
+			query.Del(name)
+		}
+		parsedURL.RawQuery = query.Encode()
+	}
+	return parsedURL.String()
+}
+
+// evictProcessed forgets processed segments older than every TTL and, when
+// maxTracked is set, the least recently processed segments beyond that many.
+// The caller must hold h.mu.
+func (h *HLSWarmer) evictProcessed() {
+	ttl := h.maxTTL()
+	for key, last := range h.processedURLs {
+		if time.Since(last) > ttl {
+			delete(h.processedURLs, key)
+		}
+	}
+
+	excess := len(h.processedURLs) - h.maxTracked
+	if h.maxTracked <= 0 || excess <= 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(h.processedURLs))
+	for key := range h.processedURLs {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return h.processedURLs[a].Compare(h.processedURLs[b])
+	})
+	for _, key := range keys[:excess] {
+		delete(h.processedURLs, key)
+	}
+}
+
+// autoInterval derives a polling interval of half the target duration, clamped to
+// sane bounds. It returns 0 when the target duration is unknown.
+func autoInterval(targetDuration time.Duration) time.Duration {
+	if targetDuration <= 0 {
+		return 0
+	}
+	return min(max(targetDuration/2, minAutoInterval), maxAutoInterval)
+}
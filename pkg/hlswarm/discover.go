@@ -0,0 +1,98 @@
+package hlswarm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// indexPlaylists are the well-known playlist names a directory URL is taken to
+// mean, in order of preference
+var indexPlaylists = []string{"master.m3u8", "index.m3u8", "playlist.m3u8"}
+
+// hrefAttribute matches the link targets of an HTML page
+var hrefAttribute = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#]+)["']`)
+
+// isHTMLResponse reports whether a manifest response is an HTML page, such as an
+// error page served for a playlist, judged by its content type or an <html> element
+func isHTMLResponse(contentType string, body []byte) bool {
+	if strings.Contains(contentType, "text/html") {
+		return true
+	}
+	trimmed := bytes.ToLower(bytes.TrimSpace(body))
+	return bytes.HasPrefix(trimmed, []byte("<!doctype html")) || bytes.HasPrefix(trimmed, []byte("<html"))
+}
+
+// isM3U8Body reports whether a response body starts with the #EXTM3U header
+func isM3U8Body(body []byte) bool {
+	return bytes.HasPrefix(bytes.TrimPrefix(bytes.TrimSpace(body), []byte("\ufeff")), []byte("#EXTM3U"))
+}
+
+// isDirectoryURL reports whether a URL names a directory, i.e. its path is empty
+// or ends in "/"
+func isDirectoryURL(rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	return err == nil && (parsedURL.Path == "" || strings.HasSuffix(parsedURL.Path, "/"))
+}
+
+// discoverPlaylists finds the playlists a directory URL stands for. When its HTML
+// listing links to a well-known index (master.m3u8, index.m3u8 or playlist.m3u8)
+// only that one is warmed, otherwise every linked .m3u8 in page order. Without any
+// links, the well-known names are tried under the directory in turn.
+func (h *HLSWarmer) discoverPlaylists(ctx context.Context, stream Stream, dirURL string, body []byte) ([]streamVariant, error) {
+	baseURL, err := url.Parse(h.resolveBase(dirURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	seen := make(map[string]bool)
+	for _, match := range hrefAttribute.FindAllSubmatch(body, -1) {
+		link, err := url.Parse(resolveURL(baseURL, cleanURI(string(match[1]))))
+		if err != nil || !strings.EqualFold(path.Ext(link.Path), ".m3u8") || seen[link.String()] {
+			continue
+		}
+		seen[link.String()] = true
+		links = append(links, link.String())
+	}
+
+	for _, name := range indexPlaylists {
+		for _, link := range links {
+			if linkURL, _ := url.Parse(link); strings.EqualFold(path.Base(linkURL.Path), name) {
+				h.log.Info("Discovered index playlist in directory", Icon("📂"), "directory", dirURL, "playlist", link)
+				return []streamVariant{{URL: link}}, nil
+			}
+		}
+	}
+
+	if len(links) > 0 {
+		h.log.Info("Discovered playlists in directory", Icon("📂"), "directory", dirURL, "playlists", len(links))
+		children := make([]streamVariant, len(links))
+		for i, link := range links {
+			children[i] = streamVariant{URL: link}
+		}
+		return children, nil
+	}
+
+	for _, name := range indexPlaylists {
+		candidate := resolveURL(baseURL, name)
+		candidateBody, _, _, err := h.fetchManifest(ctx, stream, candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		if isM3U8Body(candidateBody) {
+			h.log.Info("Discovered index playlist in directory", Icon("📂"), "directory", dirURL, "playlist", candidate)
+			return []streamVariant{{URL: candidate}}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no playlist found at %s: expected an M3U8, a page linking to .m3u8 files or one of %s",
+		dirURL, strings.Join(indexPlaylists, ", "))
+}
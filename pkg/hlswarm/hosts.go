@@ -0,0 +1,71 @@
+package hlswarm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// hostFilter decides which hosts segments and variant playlists may be fetched
+// from. A host is allowed when it matches no deny pattern and, if any allow
+// patterns are given, at least one of them.
+type hostFilter struct {
+	allow []string
+	deny  []string
+}
+
+// active reports whether the filter restricts any hosts
+func (f hostFilter) active() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// allowed reports whether the host of rawURL may be fetched; unparsable URLs are
+// left for the request to fail on
+func (f hostFilter) allowed(rawURL string) bool {
+	if !f.active() {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, pattern := range f.deny {
+		if matchHost(host, pattern) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, pattern := range f.allow {
+		if matchHost(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost matches a host against a pattern: either an exact host name, or
+// "*.example.com" to match any subdomain of example.com
+func matchHost(host, pattern string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
+
+// filterHosts drops the segments on hosts the filter disallows and returns the
+// number dropped
+func (f hostFilter) filterHosts(segments []Segment) ([]Segment, int) {
+	if !f.active() {
+		return segments, 0
+	}
+	kept := make([]Segment, 0, len(segments))
+	for _, segment := range segments {
+		if f.allowed(segment.URL) {
+			kept = append(kept, segment)
+		}
+	}
+	return kept, len(segments) - len(kept)
+}
@@ -0,0 +1,496 @@
+package hlswarm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// makeRequest creates and executes an HTTP request with the stream's headers, aborting
+// it when ctx is cancelled.
+// A non-nil byteRange restricts the request to that range of the resource.
+func (h *HLSWarmer) makeRequest(ctx context.Context, stream Stream, method, url string, byteRange *ByteRange) (*http.Response, error) {
+	// A stream's request template replaces the method and adds a body
+	cached, manifest := manifestFromContext(ctx)
+	var body io.Reader
+	template := stream.requestTemplate(manifest)
+	if template != nil {
+		method = template.method()
+		body = strings.NewReader(template.body(url, h.playbackID))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if template != nil && template.ContentType != "" {
+		req.Header.Set("Content-Type", template.ContentType)
+	}
+
+	userAgent := userAgentFromContext(ctx)
+	if userAgent == "" {
+		userAgent = h.nextUserAgent()
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "*/*")
+
+	// Look like a browser's media request unless -minimal-headers is set
+	if !h.minimalHeaders {
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		req.Header.Set("Sec-Fetch-Dest", "video")
+		req.Header.Set("Sec-Fetch-Mode", "no-cors")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("Priority", "u=3, i")
+	}
+
+	// Set range header for byte-range segments
+	if byteRange != nil {
+		req.Header.Set("Range", byteRange.header())
+	}
+
+	// Segments are requested with the configured Accept-Encoding, else gzip. Asking
+	// for it explicitly stops the transport from decoding it transparently, so the
+	// body is counted as transferred before discardBody decodes it. Manifests leave
+	// it to the transport.
+	if !manifest {
+		encoding := h.acceptEncoding
+		if encoding == "" {
+			encoding = "gzip"
+		}
+		req.Header.Set("Accept-Encoding", encoding)
+	}
+
+	// Revalidate a manifest fetched before instead of downloading it again
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	// Set referer header if provided
+	if stream.Referer != "" {
+		req.Header.Set("Referer", stream.Referer)
+	}
+
+	// Set origin header if provided
+	if stream.Origin != "" {
+		req.Header.Set("Origin", stream.Origin)
+	}
+
+	// Basic auth credentials only go to the playlist's host so that they never leak
+	// to third-party segment hosts
+	if h.basicAuth != nil && req.URL.Host == hostOf(h.resolveBase(stream.URL)) {
+		password, _ := h.basicAuth.Password()
+		req.SetBasicAuth(h.basicAuth.Username(), password)
+	}
+
+	// Set playback session ID header
+	if h.playbackID != "" {
+		req.Header.Set("X-Playback-Session-Id", h.playbackID)
+	}
+
+	// Cookies given on the command line apply to every host; the client's jar adds domain cookies
+	for _, cookie := range h.cookies {
+		req.AddCookie(cookie)
+	}
+
+	// Custom headers come last so they can override any of the defaults
+	for key, value := range h.headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+
+	// Debug output
+	if h.log.Enabled(ctx, slog.LevelDebug) {
+		headers := make([]any, 0, len(req.Header))
+		for key, values := range req.Header {
+			value := strings.Join(values, ", ")
+			if h.redactHeaders[http.CanonicalHeaderKey(key)] {
+				value = "***"
+			}
+			headers = append(headers, slog.String(key, value))
+		}
+		h.log.Debug("Making request", "method", method, "url", url, slog.Group("headers", headers...))
+	}
+
+	return h.clientFor(ctx).Do(req)
+}
+
+// defaultRedactHeaders are the request headers whose values are never logged
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Auth-Token"}
+
+// redactSet returns the canonical names of the default and extra headers to redact
+func redactSet(extra []string) map[string]bool {
+	redact := make(map[string]bool, len(defaultRedactHeaders)+len(extra))
+	for _, name := range slices.Concat(defaultRedactHeaders, extra) {
+		redact[http.CanonicalHeaderKey(name)] = true
+	}
+	return redact
+}
+
+// nextUserAgent returns the User-Agents in turn, so that requests are spread
+// across the cache variants an origin keeps per device class
+func (h *HLSWarmer) nextUserAgent() string {
+	i := h.userAgentIndex.Add(1) - 1
+	return h.userAgents[i%uint64(len(h.userAgents))]
+}
+
+// userAgentKey is the context key carrying the User-Agent a request is sent with
+type userAgentKey struct{}
+
+// withUserAgent returns a context whose requests are sent with the given User-Agent
+func withUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, userAgent)
+}
+
+// userAgentFromContext returns the User-Agent set by withUserAgent, or "" to rotate
+func userAgentFromContext(ctx context.Context) string {
+	userAgent, _ := ctx.Value(userAgentKey{}).(string)
+	return userAgent
+}
+
+// maxCachedManifests bounds how many manifests are remembered for revalidation,
+// as tokenized playlist URLs can change on every poll
+const maxCachedManifests = 256
+
+// cachedManifest is the last copy of a manifest downloaded with an ETag or
+// Last-Modified validator, so later polls can be conditional requests
+type cachedManifest struct {
+	etag         string
+	lastModified string
+	body         []byte
+	contentType  string
+	// playlist is the media playlist parsed from body, reused while the manifest
+	// is not modified
+	playlist *Playlist
+}
+
+// manifestKey is the context key marking manifest requests; its value is the
+// cached copy the request revalidates, or nil
+type manifestKey struct{}
+
+// manifestFromContext reports whether a request fetches a manifest, and returns
+// the cached copy it revalidates, if any
+func manifestFromContext(ctx context.Context) (*cachedManifest, bool) {
+	cached, ok := ctx.Value(manifestKey{}).(*cachedManifest)
+	return cached, ok
+}
+
+// detectCacheHit detects if a response was served from cache
+func (h *HLSWarmer) detectCacheHit(resp *http.Response) bool {
+	// A configured rule takes precedence over the built-in heuristics whenever its header is present
+	if h.cacheHeader != "" {
+		if value := resp.Header.Get(h.cacheHeader); value != "" {
+			return strings.Contains(strings.ToLower(value), strings.ToLower(h.cacheHitValue))
+		}
+	} else if detectCacheHitHeaders(resp) {
+		return true
+	}
+
+	// If Age header exists, it might be from cache
+	if h.ageFallback {
+		if age := resp.Header.Get("Age"); age != "" && age != "0" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// detectCacheHitHeaders checks well-known CDN cache headers for a hit indicator
+func detectCacheHitHeaders(resp *http.Response) bool {
+	// Check various headers to detect cache status
+	cacheHeaders := []string{
+		"X-Cache",
+		"X-Cache-Status",
+		"X-Served-By",
+		"CF-Cache-Status", // Cloudflare
+		"X-Fastly-Cache",  // Fastly
+		"X-Varnish-Cache", // Varnish
+		"Age",
+	}
+
+	for _, header := range cacheHeaders {
+		value := resp.Header.Get(header)
+		if value != "" {
+			// Cache hit indicators
+			hitIndicators := []string{"hit", "HIT", "cached", "CACHED"}
+			for _, indicator := range hitIndicators {
+				if strings.Contains(strings.ToLower(value), strings.ToLower(indicator)) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// errTooManyRedirects is reported when a request is redirected more than -max-redirects times
+var errTooManyRedirects = errors.New("too many redirects")
+
+// checkRedirect returns a CheckRedirect hook that fails a request once it has
+// been redirected more than maxRedirects times, listing the chain so that
+// redirect loops are easy to spot. Redirects to hosts that are not allowed fail too.
+func checkRedirect(maxRedirects int, hosts hostFilter) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if !hosts.allowed(req.URL.String()) {
+			return fmt.Errorf("redirect to disallowed host %s", req.URL.Host)
+		}
+		if len(via) <= maxRedirects {
+			return nil
+		}
+		chain := make([]string, 0, len(via)+1)
+		for _, prev := range via {
+			chain = append(chain, prev.URL.String())
+		}
+		chain = append(chain, req.URL.String())
+		return fmt.Errorf("%w: stopped after %d (%s)", errTooManyRedirects, maxRedirects, strings.Join(chain, " → "))
+	}
+}
+
+// redirectChain returns the URLs a response was redirected through, from the
+// original request to the one that was answered, or nil if it was not redirected
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil; req = req.Response.Request {
+		chain = append(chain, req.URL.String())
+		if req.Response == nil {
+			break
+		}
+	}
+	if len(chain) < 2 {
+		return nil
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// errBodyTooLarge is reported when a response body exceeds the configured limit
+var errBodyTooLarge = errors.New("body too large")
+
+// discardBody reads and discards the response body, failing with errBodyTooLarge
+// once more than limit bytes are read (0 means unlimited). The caller then closes
+// the unread body, which drops the connection instead of draining it.
+func discardBody(resp *http.Response, limit int64) (wire, decoded int64, err error) {
+	body := io.Reader(resp.Body)
+	if limit > 0 {
+		body = io.LimitReader(body, limit+1)
+	}
+	counter := &countingReader{r: body}
+
+	decoded, err = decodeBody(io.Discard, resp.Header.Get("Content-Encoding"), counter)
+	if err == nil {
+		// Drain anything the decoder left unread so the connection can be reused
+		_, err = io.Copy(io.Discard, counter)
+	}
+	if limit > 0 && counter.n > limit {
+		return counter.n, decoded, fmt.Errorf("%w: exceeds %d bytes", errBodyTooLarge, limit)
+	}
+	return counter.n, decoded, err
+}
+
+// fetchManifest downloads a playlist or MPD and returns its decoded body and
+// Content-Type. Local file:// manifests are read from disk instead. Manifests
+// served with an ETag or Last-Modified header are remembered and revalidated on
+// the next fetch; when the server answers 304 Not Modified the remembered copy is
+// returned without downloading it again, with notModified set.
+func (h *HLSWarmer) fetchManifest(ctx context.Context, stream Stream, rawURL string) (body []byte, contentType string, notModified bool, err error) {
+	if path, ok := localPath(rawURL); ok {
+		body, err := os.ReadFile(path)
+		return body, "", false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.requestTimeout)
+	defer cancel()
+
+	h.manifestsMu.Lock()
+	cached := h.manifests[rawURL]
+	h.manifestsMu.Unlock()
+	ctx = context.WithValue(ctx, manifestKey{}, cached)
+
+	resp, err := h.makeRequest(ctx, stream, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, "", false, &rateLimitError{url: rawURL, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.body, cached.contentType, true, nil
+	}
+	// An error page is no manifest; report the status rather than its body's format
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", false, fmt.Errorf("fetching %s: HTTP %d %s", rawURL, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	body, err = readBody(resp)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	// Only successful responses are worth revalidating later
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	h.manifestsMu.Lock()
+	if resp.StatusCode == http.StatusOK && (etag != "" || lastModified != "") {
+		if _, ok := h.manifests[rawURL]; !ok && len(h.manifests) >= maxCachedManifests {
+			for evicted := range h.manifests {
+				delete(h.manifests, evicted)
+				break
+			}
+		}
+		h.manifests[rawURL] = &cachedManifest{etag: etag, lastModified: lastModified, body: body, contentType: resp.Header.Get("Content-Type")}
+	} else {
+		delete(h.manifests, rawURL)
+	}
+	h.manifestsMu.Unlock()
+
+	return body, resp.Header.Get("Content-Type"), false, nil
+}
+
+// parsedManifest returns a copy of the media playlist last parsed from a manifest
+// remembered for revalidation, or nil
+func (h *HLSWarmer) parsedManifest(rawURL string) *Playlist {
+	h.manifestsMu.Lock()
+	defer h.manifestsMu.Unlock()
+
+	cached := h.manifests[rawURL]
+	if cached == nil || cached.playlist == nil {
+		return nil
+	}
+	return cached.playlist.clone()
+}
+
+// storeParsedManifest remembers the media playlist parsed from a manifest, so it
+// is reused while the server reports the manifest unchanged
+func (h *HLSWarmer) storeParsedManifest(rawURL string, playlist *Playlist) {
+	h.manifestsMu.Lock()
+	defer h.manifestsMu.Unlock()
+
+	if cached := h.manifests[rawURL]; cached != nil {
+		cached.playlist = playlist.clone()
+	}
+}
+
+// resolveBase returns the URL that references in a manifest resolve against: the
+// manifest's own URL, or -base-url for local manifests when it is set
+func (h *HLSWarmer) resolveBase(manifestURL string) string {
+	if _, ok := localPath(manifestURL); ok && h.baseURL != "" {
+		return h.baseURL
+	}
+	return manifestURL
+}
+
+// readBody reads a whole response body, decoding it when the server compressed it.
+// The transport only decompresses transparently when it set Accept-Encoding itself,
+// which it does not when -header sets one.
+func readBody(resp *http.Response) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := decodeBody(&buf, resp.Header.Get("Content-Encoding"), resp.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeBody copies a body with the given Content-Encoding to dst until its end and
+// returns its decoded size. Only gzip is decoded; other encodings are copied as-is.
+func decodeBody(dst io.Writer, encoding string, body io.Reader) (int64, error) {
+	if !strings.EqualFold(strings.TrimSpace(encoding), "gzip") {
+		return io.Copy(dst, body)
+	}
+
+	reader, err := gzip.NewReader(body)
+	if errors.Is(err, io.EOF) {
+		return 0, nil // empty body, e.g. a 304
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid gzip body: %v", err)
+	}
+	defer reader.Close()
+	return io.Copy(dst, reader)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// rateLimitError is returned when a playlist request is rejected with 429 Too Many Requests
+type rateLimitError struct {
+	url        string
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	if e.retryAfter > 0 {
+		return fmt.Sprintf("rate limited by %s (retry after %v)", e.url, e.retryAfter)
+	}
+	return fmt.Sprintf("rate limited by %s", e.url)
+}
+
+// parseRetryAfter parses a Retry-After header value in either the delay-seconds or
+// the HTTP-date form. It returns 0 if the value is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+// capRetryAfter limits a server-requested delay to the configured maximum
+func (h *HLSWarmer) capRetryAfter(delay time.Duration) time.Duration {
+	if h.maxRetryAfter > 0 && delay > h.maxRetryAfter {
+		return h.maxRetryAfter
+	}
+	return delay
+}
+
+// hostOf returns the host (and port) of rawURL, or "" if it cannot be parsed
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
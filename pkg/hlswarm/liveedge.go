@@ -0,0 +1,84 @@
+package hlswarm
+
+import "time"
+
+const (
+	// liveEdgeRetryDivisor sets how soon -live-edge polls again after a cycle that
+	// found no new edge segment, as a fraction of the last edge segment's duration
+	liveEdgeRetryDivisor = 4
+	// minLiveEdgeWait keeps -live-edge from polling in a tight loop on playlists
+	// with tiny or bogus segment durations
+	minLiveEdgeWait = 100 * time.Millisecond
+)
+
+// liveEdge is the pacing state of a daemon stream in -live-edge mode
+type liveEdge struct {
+	// duration is the EXTINF duration of the newest edge segment warmed
+	duration time.Duration
+	// wait is how long to wait before the next cycle
+	wait time.Duration
+}
+
+// liveEdgeSegments keeps, of a live playlist's new segments, only the newest media
+// segment of each media playlist plus any new keys and init segments it needs.
+// Older segments are skipped rather than fetched: either they were warmed when
+// they were the edge, or viewers have moved past them. It also records how long
+// to wait before the next cycle: the edge segment's EXTINF duration, when the next
+// one is due, after a new edge was found, or a fraction of it otherwise.
+func (h *HLSWarmer) liveEdgeSegments(stream Stream, segments, newSegments []Segment) []Segment {
+	edges := make(map[string]Segment)
+	for _, segment := range segments {
+		if !segment.IsKey && !segment.IsInit {
+			edges[segment.Playlist] = segment
+		}
+	}
+
+	var kept []Segment
+	var edgeDuration time.Duration
+	for _, segment := range newSegments {
+		if segment.IsKey || segment.IsInit {
+			kept = append(kept, segment)
+			continue
+		}
+		if edge := edges[segment.Playlist]; edge.key() == segment.key() {
+			kept = append(kept, segment)
+			if edgeDuration == 0 || (segment.Duration > 0 && segment.Duration < edgeDuration) {
+				edgeDuration = segment.Duration
+			}
+		}
+	}
+	if skipped := countMedia(newSegments) - countMedia(kept); skipped > 0 {
+		h.log.Info("Skipped segments behind the live edge", Icon("⏭️"), "stream", stream.URL, "count", skipped)
+	}
+
+	h.streamMu.Lock()
+	state, ok := h.streamEdges[stream.URL]
+	if !ok {
+		state = &liveEdge{}
+		h.streamEdges[stream.URL] = state
+	}
+	if edgeDuration > 0 {
+		state.duration = edgeDuration
+		state.wait = edgeDuration
+	} else {
+		state.wait = state.duration / liveEdgeRetryDivisor
+	}
+	state.wait = max(state.wait, minLiveEdgeWait)
+	wait := state.wait
+	h.streamMu.Unlock()
+
+	h.log.Debug("Next live edge check", "stream", stream.URL, "wait", wait, "new_edge", edgeDuration > 0)
+	return kept
+}
+
+// liveEdgeWait returns how long -live-edge waits before a stream's next cycle, and
+// false until a cycle has found the stream's live edge
+func (h *HLSWarmer) liveEdgeWait(m3u8URL string) (time.Duration, bool) {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	state, ok := h.streamEdges[m3u8URL]
+	if !ok || state.duration == 0 {
+		return 0, false
+	}
+	return state.wait, true
+}
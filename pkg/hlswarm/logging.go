@@ -0,0 +1,146 @@
+package hlswarm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Log formats: text is the human-readable console format with emoji icons,
+// json emits one structured record per line for log aggregation
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// iconKey is the attribute carrying a log line's emoji; only the text format shows it
+const iconKey = "icon"
+
+// Icon attaches an emoji to a log record for the text format
+func Icon(emoji string) slog.Attr {
+	return slog.String(iconKey, emoji)
+}
+
+// newLogger creates a logger writing records of at least level to w in the given format
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	if format == LogFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == iconKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+	}
+	return slog.New(&consoleHandler{w: w, mu: &sync.Mutex{}, level: level})
+}
+
+// consoleHandler renders records as "<icon> <message> key=value ..." lines,
+// falling back to a per-level icon when the record carries none
+type consoleHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	level  slog.Level
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (c *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= c.level
+}
+
+func (c *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	emoji := levelIcon(record.Level)
+	var buf bytes.Buffer
+
+	writeAttr := func(prefix string, a slog.Attr) {
+		if a.Key == iconKey {
+			emoji = a.Value.String()
+			return
+		}
+		appendAttr(&buf, prefix, a)
+	}
+	for _, a := range c.attrs {
+		writeAttr("", a)
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		writeAttr(c.prefix, a)
+		return true
+	})
+
+	line := record.Message
+	if emoji != "" {
+		line = emoji + " " + line
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.w, "%s%s\n", line, buf.String())
+	return err
+}
+
+func (c *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *c
+	handler.attrs = append([]slog.Attr{}, c.attrs...)
+	for _, a := range attrs {
+		if a.Key != iconKey {
+			a.Key = c.prefix + a.Key
+		}
+		handler.attrs = append(handler.attrs, a)
+	}
+	return &handler
+}
+
+func (c *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return c
+	}
+	handler := *c
+	handler.prefix = c.prefix + name + "."
+	return &handler
+}
+
+// appendAttr writes " key=value", flattening groups into dotted keys
+func appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(buf, prefix, ga)
+		}
+		return
+	}
+
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(buf, " %s%s=%s", prefix, a.Key, value)
+}
+
+// levelIcon returns the default emoji for records without an explicit icon
+func levelIcon(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "❌"
+	case level >= slog.LevelWarn:
+		return "⚠️"
+	case level < slog.LevelInfo:
+		return "🐛"
+	default:
+		return ""
+	}
+}
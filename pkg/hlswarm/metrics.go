@@ -0,0 +1,185 @@
+package hlswarm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the Prometheus collectors updated while warming
+type metrics struct {
+	registry      *prometheus.Registry
+	segments      *prometheus.CounterVec
+	hits          *prometheus.CounterVec
+	misses        *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	activeStreams prometheus.Gauge
+	hitRatio      *prometheus.GaugeVec
+	stale         *prometheus.GaugeVec
+	latency       *prometheus.GaugeVec
+	gaps          *prometheus.CounterVec
+	missing       *prometheus.CounterVec
+}
+
+// newMetrics creates and registers the warmer's Prometheus collectors
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		segments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hls_warmer_segments_warmed_total",
+			Help: "Number of segment requests completed.",
+		}, []string{"host"}),
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hls_warmer_cache_hits_total",
+			Help: "Number of segment requests served from cache.",
+		}, []string{"host"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hls_warmer_cache_misses_total",
+			Help: "Number of segment requests not served from cache.",
+		}, []string{"host"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hls_warmer_errors_total",
+			Help: "Number of segment requests that failed.",
+		}, []string{"host"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hls_warmer_segment_duration_seconds",
+			Help:    "Duration of segment requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host"}),
+		activeStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "hls_warmer_active_streams",
+			Help: "Number of streams currently warmed by the daemon.",
+		}),
+		hitRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "hls_warmer_stream_hit_ratio",
+			Help: "Overall cache hit ratio of a stream across daemon cycles.",
+		}, []string{"stream"}),
+		stale: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "hls_warmer_stream_stale",
+			Help: "Whether a live stream's playlist stopped producing new segments (1) or not (0).",
+		}, []string{"stream"}),
+		latency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "hls_warmer_stream_latency_seconds",
+			Help: "Segment request duration percentiles of a stream's latest daemon cycle.",
+		}, []string{"stream", "quantile"}),
+		gaps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hls_warmer_sequence_gaps_total",
+			Help: "Number of media sequence gaps found by -check-continuity.",
+		}, []string{"stream"}),
+		missing: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hls_warmer_missing_segments_total",
+			Help: "Number of playlist segments that returned 404 under -check-continuity.",
+		}, []string{"stream"}),
+	}
+
+	m.registry.MustRegister(m.segments, m.hits, m.misses, m.errors, m.duration, m.activeStreams, m.hitRatio, m.stale, m.latency, m.gaps, m.missing)
+	return m
+}
+
+// observeResults records a batch of segment results for the given stream
+func (m *metrics) observeResults(m3u8URL string, results []CacheStatus) {
+	if m == nil {
+		return
+	}
+
+	host := streamHost(m3u8URL)
+	for _, r := range results {
+		m.segments.WithLabelValues(host).Inc()
+		m.duration.WithLabelValues(host).Observe(r.Duration.Seconds())
+
+		switch {
+		case r.Error != nil:
+			m.errors.WithLabelValues(host).Inc()
+		case r.Hit:
+			m.hits.WithLabelValues(host).Inc()
+		default:
+			m.misses.WithLabelValues(host).Inc()
+		}
+	}
+}
+
+// observeHitRatio records a stream's overall hit ratio
+func (m *metrics) observeHitRatio(m3u8URL string, ratio float64) {
+	if m != nil {
+		m.hitRatio.WithLabelValues(m3u8URL).Set(ratio)
+	}
+}
+
+// observeLatency records the request duration percentiles of a stream's cycle
+func (m *metrics) observeLatency(result *WarmResult) {
+	if m == nil {
+		return
+	}
+	m.latency.WithLabelValues(result.M3U8URL, "0.5").Set(result.P50.Seconds())
+	m.latency.WithLabelValues(result.M3U8URL, "0.95").Set(result.P95.Seconds())
+	m.latency.WithLabelValues(result.M3U8URL, "0.99").Set(result.P99.Seconds())
+}
+
+// observeContinuity records the sequence gaps and missing segments found in a stream
+func (m *metrics) observeContinuity(m3u8URL string, gaps, missing int) {
+	if m == nil {
+		return
+	}
+	m.gaps.WithLabelValues(m3u8URL).Add(float64(gaps))
+	m.missing.WithLabelValues(m3u8URL).Add(float64(missing))
+}
+
+// observeStale records whether a stream is stale
+func (m *metrics) observeStale(m3u8URL string, stale bool) {
+	if m == nil {
+		return
+	}
+	value := 0.0
+	if stale {
+		value = 1
+	}
+	m.stale.WithLabelValues(m3u8URL).Set(value)
+}
+
+// streamStarted and streamStopped track the number of active daemon streams
+func (m *metrics) streamStarted() {
+	if m != nil {
+		m.activeStreams.Inc()
+	}
+}
+
+func (m *metrics) streamStopped() {
+	if m != nil {
+		m.activeStreams.Dec()
+	}
+}
+
+// serve exposes the metrics on addr until the context is cancelled
+func (m *metrics) serve(ctx context.Context, addr string, logger *slog.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Warn("Metrics server error", "error", err)
+	}
+}
+
+// streamHost returns the host of a stream URL for use as a metric label
+func streamHost(m3u8URL string) string {
+	parsedURL, err := url.Parse(m3u8URL)
+	if err != nil || parsedURL.Host == "" {
+		return "unknown"
+	}
+	return parsedURL.Host
+}
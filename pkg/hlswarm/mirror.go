@@ -0,0 +1,119 @@
+package hlswarm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Mirror is an origin host serving the same content as a stream's own, warmed
+// with a share of the segment requests proportional to Weight
+type Mirror struct {
+	Host   string `yaml:"host" json:"host"`
+	Weight int    `yaml:"weight" json:"weight"`
+}
+
+// ParseMirror parses a mirror written as "host[:port][=weight]", where the weight
+// defaults to 1
+func ParseMirror(value string) (Mirror, error) {
+	host, weightText, hasWeight := strings.Cut(strings.TrimSpace(value), "=")
+	mirror := Mirror{Host: strings.TrimSpace(host), Weight: 1}
+	if hasWeight {
+		weight, err := strconv.Atoi(strings.TrimSpace(weightText))
+		if err != nil {
+			return Mirror{}, fmt.Errorf("invalid weight %q", weightText)
+		}
+		mirror.Weight = weight
+	}
+	return mirror, mirror.validate()
+}
+
+// validate checks that a mirror names a bare host and has a positive weight
+func (m Mirror) validate() error {
+	if m.Host == "" || strings.ContainsAny(m.Host, "/?#@") {
+		return fmt.Errorf("invalid mirror host %q: use host or host:port", m.Host)
+	}
+	if m.Weight <= 0 {
+		return fmt.Errorf("mirror %s: weight must be positive", m.Host)
+	}
+	return nil
+}
+
+// mirrorPicker spreads requests across mirrors with smooth weighted round-robin,
+// interleaving the hosts instead of sending runs of requests to the heaviest one
+type mirrorPicker struct {
+	mirrors []Mirror
+	current []int
+	total   int
+}
+
+// newMirrorPicker creates a picker over the given mirrors
+func newMirrorPicker(mirrors []Mirror) *mirrorPicker {
+	p := &mirrorPicker{mirrors: mirrors, current: make([]int, len(mirrors))}
+	for _, m := range mirrors {
+		p.total += m.Weight
+	}
+	return p
+}
+
+// next returns the host of the mirror that receives the next request
+func (p *mirrorPicker) next() string {
+	best := 0
+	for i, m := range p.mirrors {
+		p.current[i] += m.Weight
+		if p.current[i] > p.current[best] {
+			best = i
+		}
+	}
+	p.current[best] -= p.total
+	return p.mirrors[best].Host
+}
+
+// nextMirror returns the mirror host the stream's next segment request goes to,
+// or "" when the stream has no mirrors. Each stream keeps its own rotation, which
+// restarts when its mirrors change.
+func (h *HLSWarmer) nextMirror(stream Stream) string {
+	if len(stream.Mirrors) == 0 {
+		return ""
+	}
+
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	picker, ok := h.streamMirrors[stream.URL]
+	if !ok || !slices.Equal(picker.mirrors, stream.Mirrors) {
+		picker = newMirrorPicker(stream.Mirrors)
+		h.streamMirrors[stream.URL] = picker
+	}
+	return picker.next()
+}
+
+// withMirrorHost returns the URL with its host replaced by the mirror's, keeping
+// the scheme, path and query
+func withMirrorHost(rawURL, host string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || host == "" {
+		return rawURL
+	}
+	parsedURL.Host = host
+	return parsedURL.String()
+}
+
+// mirrorKey is the context key carrying the mirror a segment request is sent to
+type mirrorKey struct{}
+
+// withMirror returns a context whose segment results are attributed to the given mirror
+func withMirror(ctx context.Context, host string) context.Context {
+	if host == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, mirrorKey{}, host)
+}
+
+// mirrorFromContext returns the mirror set by withMirror, or "" without mirrors
+func mirrorFromContext(ctx context.Context) string {
+	host, _ := ctx.Value(mirrorKey{}).(string)
+	return host
+}
@@ -0,0 +1,573 @@
+package hlswarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxMPDSegments bounds the segments enumerated per representation, guarding
+	// against manifests describing effectively endless presentations
+	maxMPDSegments = 100000
+	// defaultMPDLiveWindow is how much of a dynamic manifest is warmed when it does
+	// not declare a timeShiftBufferDepth
+	defaultMPDLiveWindow = 60 * time.Second
+)
+
+// mpd is the subset of a DASH Media Presentation Description needed to enumerate segments
+type mpd struct {
+	Type                      string      `xml:"type,attr"`
+	MediaPresentationDuration string      `xml:"mediaPresentationDuration,attr"`
+	MinimumUpdatePeriod       string      `xml:"minimumUpdatePeriod,attr"`
+	AvailabilityStartTime     string      `xml:"availabilityStartTime,attr"`
+	TimeShiftBufferDepth      string      `xml:"timeShiftBufferDepth,attr"`
+	BaseURL                   []string    `xml:"BaseURL"`
+	Periods                   []mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	ID             string             `xml:"id,attr"`
+	Start          string             `xml:"start,attr"`
+	Duration       string             `xml:"duration,attr"`
+	BaseURL        []string           `xml:"BaseURL"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	BaseURL         []string            `xml:"BaseURL"`
+	SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *mpdSegmentList     `xml:"SegmentList"`
+	SegmentBase     *mpdSegmentBase     `xml:"SegmentBase"`
+	Representations []mpdRepresentation `xml:"Representation"`
+}
+
+type mpdRepresentation struct {
+	ID              string              `xml:"id,attr"`
+	Bandwidth       int64               `xml:"bandwidth,attr"`
+	BaseURL         []string            `xml:"BaseURL"`
+	SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *mpdSegmentList     `xml:"SegmentList"`
+	SegmentBase     *mpdSegmentBase     `xml:"SegmentBase"`
+}
+
+type mpdSegmentTemplate struct {
+	Media                  string              `xml:"media,attr"`
+	Initialization         string              `xml:"initialization,attr"`
+	StartNumber            *int64              `xml:"startNumber,attr"`
+	Timescale              *int64              `xml:"timescale,attr"`
+	Duration               *int64              `xml:"duration,attr"`
+	PresentationTimeOffset *int64              `xml:"presentationTimeOffset,attr"`
+	SegmentTimeline        *mpdSegmentTimeline `xml:"SegmentTimeline"`
+}
+
+type mpdSegmentTimeline struct {
+	S []mpdTimelineEntry `xml:"S"`
+}
+
+type mpdTimelineEntry struct {
+	T *int64 `xml:"t,attr"`
+	D int64  `xml:"d,attr"`
+	R int64  `xml:"r,attr"`
+}
+
+type mpdSegmentList struct {
+	Timescale      *int64   `xml:"timescale,attr"`
+	Duration       *int64   `xml:"duration,attr"`
+	Initialization *mpdURL  `xml:"Initialization"`
+	SegmentURLs    []mpdURL `xml:"SegmentURL"`
+}
+
+type mpdSegmentBase struct {
+	Initialization *mpdURL `xml:"Initialization"`
+}
+
+// mpdURL is an Initialization or SegmentURL element, optionally limited to a byte range
+type mpdURL struct {
+	SourceURL  string `xml:"sourceURL,attr"`
+	Range      string `xml:"range,attr"`
+	Media      string `xml:"media,attr"`
+	MediaRange string `xml:"mediaRange,attr"`
+}
+
+// parseManifest parses a stream's manifest, dispatching to the DASH parser for
+// .mpd URLs and to the HLS parser otherwise. The HLS parser still hands off to
+// the DASH parser when the response turns out to be an MPD.
+func (h *HLSWarmer) parseManifest(ctx context.Context, stream Stream) (*Playlist, error) {
+	parse := h.parseM3U8
+	if isMPDURL(stream.URL) {
+		parse = h.parseMPD
+	}
+	playlist, err := parse(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// Segments on hosts that are not allowed are never fetched
+	var disallowed int
+	playlist.Segments, disallowed = h.hosts.filterHosts(playlist.Segments)
+	playlist.Disallowed += disallowed
+	return playlist, nil
+}
+
+// parseMPD downloads a DASH manifest and enumerates the segments of every representation
+func (h *HLSWarmer) parseMPD(ctx context.Context, stream Stream) (*Playlist, error) {
+	body, _, _, err := h.fetchManifest(ctx, stream, stream.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := &Playlist{}
+	if err := parseMPDBody(h.resolveBase(stream.URL), body, playlist, time.Now()); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// isMPDURL reports whether a URL points at a DASH manifest by its extension
+func isMPDURL(rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(parsedURL.Path), ".mpd")
+}
+
+// isMPDResponse reports whether a manifest response is a DASH MPD, judged by its
+// content type or an <MPD> root element
+func isMPDResponse(contentType string, body []byte) bool {
+	if strings.Contains(contentType, "dash+xml") {
+		return true
+	}
+	trimmed := bytes.TrimSpace(body)
+	return bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(trimmed, []byte("<MPD"))
+}
+
+// parseMPDBody parses an MPD document into playlist. Each representation becomes
+// a variant; now anchors the live window of dynamic manifests.
+func parseMPDBody(manifestURL string, body []byte, playlist *Playlist, now time.Time) error {
+	var manifest mpd
+	if err := xml.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("invalid MPD: %v", err)
+	}
+
+	baseURL, err := url.Parse(manifestURL)
+	if err != nil {
+		return err
+	}
+	baseURL = resolveBaseURL(baseURL, manifest.BaseURL)
+
+	dynamic := manifest.Type == "dynamic"
+	playlist.Live = dynamic
+
+	// Dynamic manifests declare how often they change; otherwise the longest segment
+	// stands in for a target duration
+	updatePeriod, err := parseISODuration(manifest.MinimumUpdatePeriod)
+	if err == nil && updatePeriod > 0 {
+		playlist.TargetDuration = updatePeriod
+	}
+
+	presentationDuration, _ := parseISODuration(manifest.MediaPresentationDuration)
+	timeShiftDepth, err := parseISODuration(manifest.TimeShiftBufferDepth)
+	if err != nil || timeShiftDepth <= 0 {
+		timeShiftDepth = defaultMPDLiveWindow
+	}
+	var availabilityStart time.Time
+	if manifest.AvailabilityStartTime != "" {
+		availabilityStart, _ = time.Parse(time.RFC3339, manifest.AvailabilityStartTime)
+	}
+
+	seen := make(map[string]bool)
+	for i, period := range manifest.Periods {
+		periodBase := resolveBaseURL(baseURL, period.BaseURL)
+		periodStart, _ := parseISODuration(period.Start)
+
+		// A period lasts until the next one starts, else for its own or the presentation's duration
+		periodDuration, _ := parseISODuration(period.Duration)
+		if periodDuration == 0 && i+1 < len(manifest.Periods) {
+			if nextStart, err := parseISODuration(manifest.Periods[i+1].Start); err == nil && nextStart > periodStart {
+				periodDuration = nextStart - periodStart
+			}
+		}
+		if periodDuration == 0 && presentationDuration > periodStart {
+			periodDuration = presentationDuration - periodStart
+		}
+
+		window := mpdWindow{dynamic: dynamic, periodDuration: periodDuration, depth: timeShiftDepth}
+		if dynamic && !availabilityStart.IsZero() {
+			window.elapsed = now.Sub(availabilityStart.Add(periodStart))
+		}
+
+		for _, set := range period.AdaptationSets {
+			setBase := resolveBaseURL(periodBase, set.BaseURL)
+			for _, rep := range set.Representations {
+				repBase := resolveBaseURL(setBase, rep.BaseURL)
+				variantURL := manifestURL + "#" + period.ID + "/" + rep.ID
+
+				segments, maxDuration, err := representationSegments(repBase, set, rep, window)
+				if err != nil {
+					return fmt.Errorf("representation %s: %v", rep.ID, err)
+				}
+
+				media := 0
+				for _, segment := range segments {
+					segment.Playlist = variantURL
+					if segment.IsInit {
+						if seen[segment.key()] {
+							continue
+						}
+						seen[segment.key()] = true
+					} else {
+						media++
+					}
+					playlist.Segments = append(playlist.Segments, segment)
+				}
+
+				playlist.Variants = append(playlist.Variants, Variant{URL: variantURL, Bandwidth: rep.Bandwidth, Segments: media})
+				if updatePeriod <= 0 {
+					playlist.TargetDuration = max(playlist.TargetDuration, maxDuration)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// mpdWindow describes which part of a period's timeline is available
+type mpdWindow struct {
+	dynamic        bool
+	periodDuration time.Duration
+	// elapsed is the time since the period started on the wall clock (dynamic only)
+	elapsed time.Duration
+	// depth is the time-shift buffer of a dynamic manifest
+	depth time.Duration
+}
+
+// representationSegments enumerates a representation's init and media segments,
+// returning them with the longest media segment duration
+func representationSegments(base *url.URL, set mpdAdaptationSet, rep mpdRepresentation, window mpdWindow) ([]Segment, time.Duration, error) {
+	if template := mergeTemplates(set.SegmentTemplate, rep.SegmentTemplate); template != nil {
+		return templateSegments(base, template, rep, window)
+	}
+
+	list := rep.SegmentList
+	if list == nil {
+		list = set.SegmentList
+	}
+	if list != nil {
+		return listSegments(base, list)
+	}
+
+	// A single-file representation, optionally with an indexed init range
+	var segments []Segment
+	segmentBase := rep.SegmentBase
+	if segmentBase == nil {
+		segmentBase = set.SegmentBase
+	}
+	if segmentBase != nil && segmentBase.Initialization != nil && segmentBase.Initialization.Range != "" {
+		init, err := mpdURLSegment(base, base.String(), segmentBase.Initialization.Range)
+		if err != nil {
+			return nil, 0, err
+		}
+		init.IsInit = true
+		segments = append(segments, init)
+	}
+	segments = append(segments, Segment{URL: base.String()})
+	return segments, 0, nil
+}
+
+// mergeTemplates overlays a representation's SegmentTemplate on its adaptation set's
+func mergeTemplates(parent, child *mpdSegmentTemplate) *mpdSegmentTemplate {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+
+	merged := *parent
+	if child.Media != "" {
+		merged.Media = child.Media
+	}
+	if child.Initialization != "" {
+		merged.Initialization = child.Initialization
+	}
+	if child.StartNumber != nil {
+		merged.StartNumber = child.StartNumber
+	}
+	if child.Timescale != nil {
+		merged.Timescale = child.Timescale
+	}
+	if child.Duration != nil {
+		merged.Duration = child.Duration
+	}
+	if child.PresentationTimeOffset != nil {
+		merged.PresentationTimeOffset = child.PresentationTimeOffset
+	}
+	if child.SegmentTimeline != nil {
+		merged.SegmentTimeline = child.SegmentTimeline
+	}
+	return &merged
+}
+
+// templateSegments expands a SegmentTemplate into segments, either from its
+// SegmentTimeline or from a fixed segment duration
+func templateSegments(base *url.URL, template *mpdSegmentTemplate, rep mpdRepresentation, window mpdWindow) ([]Segment, time.Duration, error) {
+	if template.Media == "" {
+		return nil, 0, fmt.Errorf("SegmentTemplate has no media attribute")
+	}
+
+	timescale := int64(1)
+	if template.Timescale != nil && *template.Timescale > 0 {
+		timescale = *template.Timescale
+	}
+	startNumber := int64(1)
+	if template.StartNumber != nil {
+		startNumber = *template.StartNumber
+	}
+	toDuration := func(units int64) time.Duration {
+		return time.Duration(float64(units) / float64(timescale) * float64(time.Second))
+	}
+
+	var segments []Segment
+	if template.Initialization != "" {
+		initURL := expandTemplate(template.Initialization, rep, 0, 0)
+		segments = append(segments, Segment{URL: resolveURL(base, initURL), IsInit: true})
+	}
+
+	var maxDuration time.Duration
+	addSegment := func(number, start, duration int64) {
+		mediaURL := expandTemplate(template.Media, rep, number, start)
+		segments = append(segments, Segment{
+			URL:            resolveURL(base, mediaURL),
+			Duration:       toDuration(duration),
+			Start:          toDuration(start),
+			SequenceNumber: number,
+			HasSequence:    true,
+		})
+		maxDuration = max(maxDuration, toDuration(duration))
+	}
+
+	if template.SegmentTimeline != nil {
+		entries, err := expandTimeline(template.SegmentTimeline, toUnits(window.periodDuration, timescale))
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, entry := range entries {
+			addSegment(startNumber+int64(i), entry.start, entry.duration)
+		}
+		return segments, maxDuration, nil
+	}
+
+	if template.Duration == nil || *template.Duration <= 0 {
+		return nil, 0, fmt.Errorf("SegmentTemplate needs a duration or SegmentTimeline")
+	}
+	duration := *template.Duration
+	segmentDuration := toDuration(duration)
+
+	// Work out the range of segment indexes that are available
+	first, count := int64(0), int64(0)
+	switch {
+	case window.dynamic && window.elapsed > 0:
+		available := int64(window.elapsed / segmentDuration)
+		count = min(available, int64(math.Ceil(float64(window.depth)/float64(segmentDuration))))
+		first = available - count
+	case window.periodDuration > 0:
+		count = int64(math.Ceil(float64(window.periodDuration) / float64(segmentDuration)))
+	default:
+		return nil, 0, fmt.Errorf("cannot determine the number of segments without a duration")
+	}
+	if count > maxMPDSegments {
+		return nil, 0, fmt.Errorf("%d segments exceed the limit of %d", count, maxMPDSegments)
+	}
+
+	offset := int64(0)
+	if template.PresentationTimeOffset != nil {
+		offset = *template.PresentationTimeOffset
+	}
+	for i := first; i < first+count; i++ {
+		addSegment(startNumber+i, offset+i*duration, duration)
+	}
+	return segments, maxDuration, nil
+}
+
+// timelineEntry is a single expanded segment of a SegmentTimeline, in timescale units
+type timelineEntry struct {
+	start    int64
+	duration int64
+}
+
+// expandTimeline expands the S elements of a SegmentTimeline, including repeats.
+// A negative repeat count lasts until the next entry or the end of the period.
+func expandTimeline(timeline *mpdSegmentTimeline, periodEnd int64) ([]timelineEntry, error) {
+	var entries []timelineEntry
+	current := int64(0)
+	for i, s := range timeline.S {
+		if s.D <= 0 {
+			return nil, fmt.Errorf("SegmentTimeline entry %d has no duration", i+1)
+		}
+		if s.T != nil {
+			current = *s.T
+		}
+
+		repeat := s.R
+		if repeat < 0 {
+			end := periodEnd
+			if i+1 < len(timeline.S) && timeline.S[i+1].T != nil {
+				end = *timeline.S[i+1].T
+			}
+			repeat = 0
+			if end > current {
+				repeat = (end-current+s.D-1)/s.D - 1
+			}
+		}
+
+		for j := int64(0); j <= repeat; j++ {
+			if len(entries) >= maxMPDSegments {
+				return nil, fmt.Errorf("SegmentTimeline exceeds the limit of %d segments", maxMPDSegments)
+			}
+			entries = append(entries, timelineEntry{start: current, duration: s.D})
+			current += s.D
+		}
+	}
+	return entries, nil
+}
+
+// listSegments returns the segments of an explicit SegmentList
+func listSegments(base *url.URL, list *mpdSegmentList) ([]Segment, time.Duration, error) {
+	var segments []Segment
+	if list.Initialization != nil {
+		init, err := mpdURLSegment(base, list.Initialization.SourceURL, list.Initialization.Range)
+		if err != nil {
+			return nil, 0, err
+		}
+		init.IsInit = true
+		segments = append(segments, init)
+	}
+
+	var duration time.Duration
+	if list.Duration != nil {
+		timescale := int64(1)
+		if list.Timescale != nil && *list.Timescale > 0 {
+			timescale = *list.Timescale
+		}
+		duration = time.Duration(float64(*list.Duration) / float64(timescale) * float64(time.Second))
+	}
+
+	for i, segmentURL := range list.SegmentURLs {
+		segment, err := mpdURLSegment(base, segmentURL.Media, segmentURL.MediaRange)
+		if err != nil {
+			return nil, 0, err
+		}
+		segment.Duration = duration
+		segment.Start = time.Duration(i) * duration
+		segments = append(segments, segment)
+	}
+	return segments, duration, nil
+}
+
+// mpdURLSegment builds a segment from a URL attribute, which defaults to the base
+// URL when empty, and an optional "first-last" byte range
+func mpdURLSegment(base *url.URL, ref, byteRange string) (Segment, error) {
+	segment := Segment{URL: base.String()}
+	if ref != "" {
+		segment.URL = resolveURL(base, ref)
+	}
+
+	if byteRange != "" {
+		firstStr, lastStr, ok := strings.Cut(byteRange, "-")
+		first, err1 := strconv.ParseInt(firstStr, 10, 64)
+		last, err2 := strconv.ParseInt(lastStr, 10, 64)
+		if !ok || err1 != nil || err2 != nil || last < first {
+			return Segment{}, fmt.Errorf("invalid byte range %q", byteRange)
+		}
+		segment.ByteRange = &ByteRange{Length: last - first + 1, Offset: first}
+	}
+	return segment, nil
+}
+
+// templateIdentifier matches $Identifier$ and $Identifier%0Nd$ placeholders, and $$
+var templateIdentifier = regexp.MustCompile(`\$(RepresentationID|Number|Bandwidth|Time)?(%0\d+d)?\$`)
+
+// expandTemplate substitutes the DASH template identifiers in a media or
+// initialization template
+func expandTemplate(template string, rep mpdRepresentation, number, start int64) string {
+	return templateIdentifier.ReplaceAllStringFunc(template, func(match string) string {
+		parts := templateIdentifier.FindStringSubmatch(match)
+		name, format := parts[1], parts[2]
+		if format == "" {
+			format = "%d"
+		}
+
+		switch name {
+		case "":
+			return "$"
+		case "RepresentationID":
+			return rep.ID
+		case "Number":
+			return fmt.Sprintf(format, number)
+		case "Bandwidth":
+			return fmt.Sprintf(format, rep.Bandwidth)
+		case "Time":
+			return fmt.Sprintf(format, start)
+		}
+		return match
+	})
+}
+
+// resolveBaseURL applies the first BaseURL element, if any, to base
+func resolveBaseURL(base *url.URL, baseURLs []string) *url.URL {
+	if len(baseURLs) == 0 || strings.TrimSpace(baseURLs[0]) == "" {
+		return base
+	}
+	resolved, err := url.Parse(resolveURL(base, strings.TrimSpace(baseURLs[0])))
+	if err != nil {
+		return base
+	}
+	return resolved
+}
+
+// toUnits converts a duration to timescale units
+func toUnits(d time.Duration, timescale int64) int64 {
+	return int64(d.Seconds() * float64(timescale))
+}
+
+// isoDuration matches the xs:duration values used by MPDs, e.g. "PT1H2M3.5S" or "P1DT12H"
+var isoDuration = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration parses an xs:duration without year or month components. An
+// empty value yields 0.
+func parseISODuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	parts := isoDuration.FindStringSubmatch(value)
+	if parts == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var total time.Duration
+	for i, unit := range units {
+		if parts[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(parts[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		total += time.Duration(n * float64(unit))
+	}
+	return total, nil
+}
@@ -0,0 +1,102 @@
+package hlswarm
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracingName names the service and instrumentation scope of exported spans
+const tracingName = "hls-proxy-warm"
+
+// defaultTraceFlushTimeout bounds how long exiting waits for buffered spans to export
+const defaultTraceFlushTimeout = 5 * time.Second
+
+// NewTracerProvider returns a provider exporting spans over OTLP/HTTP to endpoint,
+// e.g. http://localhost:4318, or a no-op provider when endpoint is empty. Spans
+// are posted to /v1/traces unless the endpoint has a path of its own.
+func NewTracerProvider(endpoint string) (trace.TracerProvider, error) {
+	if endpoint == "" {
+		return noop.NewTracerProvider(), nil
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpointURL.Host)}
+	if endpointURL.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if endpointURL.Path != "" && endpointURL.Path != "/" {
+		options = append(options, otlptracehttp.WithURLPath(endpointURL.Path))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", tracingName))),
+	), nil
+}
+
+// FlushTraces exports any buffered spans; call it before exiting
+func (h *HLSWarmer) FlushTraces() {
+	provider, ok := h.tracerProvider.(*sdktrace.TracerProvider)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTraceFlushTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		h.log.Warn("Error exporting traces", "error", err)
+	}
+}
+
+// endCycleSpan records a warm cycle's totals on its span and ends it
+func endCycleSpan(span trace.Span, result *WarmResult, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if result != nil {
+		span.SetAttributes(
+			attribute.Int("hls.segments", result.TotalFiles),
+			attribute.Int("hls.cache_hits", result.CachedFiles),
+			attribute.Int("hls.errors", len(result.Errors)),
+			attribute.Int64("hls.bytes", result.WireBytes),
+		)
+	}
+	span.End()
+}
+
+// endSegmentSpan records a segment request's outcome on its span and ends it
+func endSegmentSpan(span trace.Span, status CacheStatus) {
+	span.SetAttributes(
+		attribute.Bool("hls.cache_hit", status.Hit),
+		attribute.Int64("http.response.body.size", max(status.WireBytes, 0)),
+	)
+	if status.StatusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status.StatusCode))
+	}
+	if status.Edge != "" {
+		span.SetAttributes(attribute.String("hls.edge", status.Edge))
+	}
+	if status.Error != nil {
+		span.RecordError(status.Error)
+		span.SetStatus(codes.Error, status.Error.Error())
+	} else if status.StatusCode >= 400 {
+		span.SetStatus(codes.Error, "")
+	}
+	span.End()
+}
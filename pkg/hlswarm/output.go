@@ -0,0 +1,316 @@
+package hlswarm
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Output formats for warm results
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputCSV  = "csv"
+)
+
+// jsonResult is the machine-readable form of a WarmResult
+type jsonResult struct {
+	M3U8URL           string            `json:"m3u8_url"`
+	TotalFiles        int               `json:"total_files"`
+	CachedFiles       int               `json:"cached_files"`
+	Succeeded         int               `json:"succeeded"`
+	SuccessCriteria   string            `json:"success_criteria"`
+	Suspicious        int               `json:"suspicious_files"`
+	Duplicates        int               `json:"duplicates_skipped"`
+	Disallowed        int               `json:"disallowed_skipped"`
+	Deferred          int               `json:"deferred,omitempty"`
+	StatusCodes       map[int]int       `json:"status_codes"`
+	Predicted         int               `json:"predicted,omitempty"`
+	PredictedNotFound int               `json:"predicted_not_found,omitempty"`
+	TotalBytes        int64             `json:"total_bytes"`
+	WireBytes         int64             `json:"wire_bytes"`
+	TotalDurationMS   int64             `json:"total_duration_ms"`
+	RealTimeRatio     float64           `json:"realtime_ratio"`
+	Errors            []string          `json:"errors"`
+	DurationMS        int64             `json:"duration_ms"`
+	P50MS             float64           `json:"p50_ms"`
+	P95MS             float64           `json:"p95_ms"`
+	P99MS             float64           `json:"p99_ms"`
+	ReusedConns       int               `json:"reused_connections"`
+	NewConns          int               `json:"new_connections"`
+	Variants          []Variant         `json:"variants,omitempty"`
+	Details           []jsonSegment     `json:"details"`
+	Verification      *jsonVerification `json:"verification,omitempty"`
+	Purge             *jsonPurge        `json:"purge,omitempty"`
+	Continuity        *jsonContinuity   `json:"continuity,omitempty"`
+	ParseWarnings     []ParseWarning    `json:"parse_warnings,omitempty"`
+	MethodComparison  []jsonComparison  `json:"method_comparison,omitempty"`
+	DeadlineExceeded  bool              `json:"deadline_exceeded,omitempty"`
+	Unwarmed          int               `json:"unwarmed,omitempty"`
+}
+
+// jsonVerification is the machine-readable form of a Verification
+type jsonVerification struct {
+	DelayMS     int64                 `json:"delay_ms"`
+	Hits        int                   `json:"hits"`
+	Evicted     int                   `json:"evicted"`
+	NeverCached int                   `json:"never_cached"`
+	Errors      int                   `json:"errors"`
+	Details     []jsonVerifiedSegment `json:"details"`
+}
+
+// jsonVerifiedSegment is the machine-readable form of a VerifiedSegment
+type jsonVerifiedSegment struct {
+	URL             string `json:"url"`
+	ByteRange       string `json:"byte_range,omitempty"`
+	Edge            string `json:"edge,omitempty"`
+	BeforeHit       bool   `json:"before_hit"`
+	AfterHit        bool   `json:"after_hit"`
+	AfterStatusCode int    `json:"after_status_code"`
+	Error           string `json:"error,omitempty"`
+}
+
+// jsonPurge is the machine-readable form of a Purge
+type jsonPurge struct {
+	Purged    int                 `json:"purged"`
+	NotCached int                 `json:"not_cached"`
+	Failed    int                 `json:"failed"`
+	Details   []jsonPurgedSegment `json:"details"`
+}
+
+// jsonPurgedSegment is the machine-readable form of a PurgedSegment
+type jsonPurgedSegment struct {
+	URL        string `json:"url"`
+	Edge       string `json:"edge,omitempty"`
+	StatusCode int    `json:"status_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// jsonContinuity is the machine-readable form of a Continuity
+type jsonContinuity struct {
+	Gaps    []jsonGap `json:"gaps"`
+	Missing []string  `json:"missing"`
+}
+
+// jsonGap is the machine-readable form of a SequenceGap
+type jsonGap struct {
+	Playlist string `json:"playlist"`
+	From     int64  `json:"from"`
+	To       int64  `json:"to"`
+}
+
+// jsonComparison is the machine-readable form of a MethodComparison
+type jsonComparison struct {
+	URL     string             `json:"url"`
+	Results []jsonMethodResult `json:"results"`
+}
+
+// jsonMethodResult is the machine-readable form of a MethodResult
+type jsonMethodResult struct {
+	Label      string            `json:"label"`
+	Method     string            `json:"method"`
+	StatusCode int               `json:"status_code"`
+	Hit        bool              `json:"hit"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// jsonSegment is the machine-readable form of a CacheStatus
+type jsonSegment struct {
+	URL           string      `json:"url"`
+	ByteRange     string      `json:"byte_range,omitempty"`
+	IsKey         bool        `json:"is_key,omitempty"`
+	IsInit        bool        `json:"is_init,omitempty"`
+	Partial       bool        `json:"partial,omitempty"`
+	StatusCode    int         `json:"status_code"`
+	Hit           bool        `json:"hit"`
+	DurationMS    int64       `json:"duration_ms"`
+	Attempts      int         `json:"attempts"`
+	Discontinuity int64       `json:"discontinuity"`
+	Edge          string      `json:"edge,omitempty"`
+	Mirror        string      `json:"mirror,omitempty"`
+	UserAgent     string      `json:"user_agent,omitempty"`
+	Proto         string      `json:"proto,omitempty"`
+	ContentType   string      `json:"content_type,omitempty"`
+	Size          int64       `json:"size"`
+	WireBytes     int64       `json:"wire_bytes"`
+	Suspicious    string      `json:"suspicious,omitempty"`
+	Redirects     []string    `json:"redirects,omitempty"`
+	Timing        *jsonTiming `json:"timing,omitempty"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// jsonTiming is the machine-readable form of a Timing, in milliseconds
+type jsonTiming struct {
+	DNSMS      float64 `json:"dns_ms"`
+	ConnectMS  float64 `json:"connect_ms"`
+	TLSMS      float64 `json:"tls_ms"`
+	TTFBMS     float64 `json:"ttfb_ms"`
+	TransferMS float64 `json:"transfer_ms"`
+	Reused     bool    `json:"reused"`
+}
+
+// PrintJSON writes a WarmResult to stdout as a single line of JSON, so that
+// successive results form an NDJSON stream
+func (h *HLSWarmer) PrintJSON(result *WarmResult) {
+	data, err := json.Marshal(newJSONResult(result))
+	if err != nil {
+		h.log.Error("Error encoding result", "error", err)
+		return
+	}
+
+	// Serialize writes so concurrent daemon streams never interleave lines
+	h.outputMu.Lock()
+	defer h.outputMu.Unlock()
+	os.Stdout.Write(append(data, '\n'))
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// newJSONResult converts a WarmResult to its machine-readable form
+func newJSONResult(result *WarmResult) jsonResult {
+	out := jsonResult{
+		M3U8URL:           result.M3U8URL,
+		TotalFiles:        result.TotalFiles,
+		CachedFiles:       result.CachedFiles,
+		Succeeded:         result.Succeeded,
+		SuccessCriteria:   result.SuccessCriteria,
+		Suspicious:        result.SuspiciousFiles,
+		Duplicates:        result.Duplicates,
+		Disallowed:        result.Disallowed,
+		ParseWarnings:     result.ParseWarnings,
+		Deferred:          result.Deferred,
+		StatusCodes:       result.StatusCodes,
+		DeadlineExceeded:  result.DeadlineExceeded,
+		Unwarmed:          result.Unwarmed,
+		Predicted:         result.Predicted,
+		PredictedNotFound: result.PredictedNotFound,
+		TotalBytes:        result.TotalBytes,
+		WireBytes:         result.WireBytes,
+		TotalDurationMS:   result.TotalDuration.Milliseconds(),
+		RealTimeRatio:     result.realTimeRatio(),
+		Errors:            make([]string, 0, len(result.Errors)),
+		DurationMS:        result.Duration.Milliseconds(),
+		P50MS:             milliseconds(result.P50),
+		P95MS:             milliseconds(result.P95),
+		P99MS:             milliseconds(result.P99),
+		ReusedConns:       result.ReusedConns,
+		NewConns:          result.NewConns,
+		Variants:          result.Variants,
+		Details:           make([]jsonSegment, 0, len(result.Details)),
+	}
+
+	for _, err := range result.Errors {
+		out.Errors = append(out.Errors, err.Error())
+	}
+
+	for _, detail := range result.Details {
+		segment := jsonSegment{
+			URL:           detail.URL,
+			IsKey:         detail.IsKey,
+			IsInit:        detail.IsInit,
+			Partial:       detail.Partial,
+			StatusCode:    detail.StatusCode,
+			Hit:           detail.Hit,
+			DurationMS:    detail.Duration.Milliseconds(),
+			Attempts:      detail.Attempts,
+			Discontinuity: detail.Discontinuity,
+			Edge:          detail.Edge,
+			Mirror:        detail.Mirror,
+			UserAgent:     detail.UserAgent,
+			Proto:         detail.Proto,
+			ContentType:   detail.ContentType,
+			Size:          detail.Size,
+			WireBytes:     detail.WireBytes,
+			Suspicious:    detail.Suspicious,
+			Redirects:     detail.Redirects,
+		}
+		if detail.ByteRange != nil {
+			segment.ByteRange = detail.ByteRange.header()
+		}
+		if detail.Timing != (Timing{}) {
+			segment.Timing = &jsonTiming{
+				DNSMS:      milliseconds(detail.Timing.DNS),
+				ConnectMS:  milliseconds(detail.Timing.Connect),
+				TLSMS:      milliseconds(detail.Timing.TLS),
+				TTFBMS:     milliseconds(detail.Timing.TTFB),
+				TransferMS: milliseconds(detail.Timing.Transfer),
+				Reused:     detail.Timing.Reused,
+			}
+		}
+		if detail.Error != nil {
+			segment.Error = detail.Error.Error()
+		}
+		out.Details = append(out.Details, segment)
+	}
+
+	if v := result.Verification; v != nil {
+		out.Verification = &jsonVerification{
+			DelayMS:     v.Delay.Milliseconds(),
+			Hits:        v.Hits,
+			Evicted:     v.Evicted,
+			NeverCached: v.NeverCached,
+			Errors:      v.Errors,
+			Details:     make([]jsonVerifiedSegment, 0, len(v.Details)),
+		}
+		for _, d := range v.Details {
+			segment := jsonVerifiedSegment{
+				URL:             d.Before.URL,
+				Edge:            d.Before.Edge,
+				BeforeHit:       d.Before.Hit,
+				AfterHit:        d.After.Hit,
+				AfterStatusCode: d.After.StatusCode,
+			}
+			if d.Before.ByteRange != nil {
+				segment.ByteRange = d.Before.ByteRange.header()
+			}
+			if d.After.Error != nil {
+				segment.Error = d.After.Error.Error()
+			}
+			out.Verification.Details = append(out.Verification.Details, segment)
+		}
+	}
+
+	if p := result.Purge; p != nil {
+		out.Purge = &jsonPurge{Purged: p.Purged, NotCached: p.NotCached, Failed: p.Failed, Details: make([]jsonPurgedSegment, 0, len(p.Details))}
+		for _, d := range p.Details {
+			segment := jsonPurgedSegment{URL: d.URL, Edge: d.Edge, StatusCode: d.StatusCode, DurationMS: d.Duration.Milliseconds()}
+			if d.Error != nil {
+				segment.Error = d.Error.Error()
+			}
+			out.Purge.Details = append(out.Purge.Details, segment)
+		}
+	}
+
+	if c := result.Continuity; c != nil {
+		out.Continuity = &jsonContinuity{Gaps: make([]jsonGap, 0, len(c.Gaps)), Missing: make([]string, 0, len(c.Missing))}
+		for _, gap := range c.Gaps {
+			out.Continuity.Gaps = append(out.Continuity.Gaps, jsonGap{Playlist: gap.Playlist, From: gap.From, To: gap.To})
+		}
+		out.Continuity.Missing = append(out.Continuity.Missing, c.Missing...)
+	}
+
+	for _, c := range result.MethodComparison {
+		comparison := jsonComparison{URL: c.URL, Results: make([]jsonMethodResult, 0, len(c.Results))}
+		for _, r := range c.Results {
+			mr := jsonMethodResult{
+				Label:      r.Label,
+				Method:     r.Status.Method,
+				StatusCode: r.Status.StatusCode,
+				Hit:        r.Status.Hit,
+				Headers:    r.Status.Headers,
+			}
+			if r.Status.Error != nil {
+				mr.Error = r.Status.Error.Error()
+			}
+			comparison.Results = append(comparison.Results, mr)
+		}
+		out.MethodComparison = append(out.MethodComparison, comparison)
+	}
+
+	return out
+}
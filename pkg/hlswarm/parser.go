@@ -0,0 +1,634 @@
+package hlswarm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPlaylistDepth bounds how deep master playlists may nest before recursion stops
+const maxPlaylistDepth = 4
+
+// minSegmentLineLength is the length below which a URI line is taken for a marker
+const minSegmentLineLength = 5
+
+// DefaultSegmentKeywords mark URLs without an extension as segments
+var DefaultSegmentKeywords = []string{"seg", "chunk"}
+
+// Playlist holds the parsed contents of an M3U8 playlist. Master playlists are
+// expanded so that Segments contains the segments of every variant.
+type Playlist struct {
+	Segments []Segment
+	Variants []Variant
+	// Live is false once a playlist carries EXT-X-ENDLIST; a master playlist is
+	// live while any of its variants is
+	Live bool
+	// TargetDuration is the largest EXT-X-TARGETDURATION across media playlists
+	TargetDuration time.Duration
+	// MediaSequence is the EXT-X-MEDIA-SEQUENCE of a media playlist, the sequence
+	// number of its first segment
+	MediaSequence int64
+	// Disallowed counts the segments and variant playlists skipped because their
+	// host is not allowed by -allow-host and -deny-host
+	Disallowed int
+	// Warnings are the well-formedness problems found in the playlists
+	Warnings []ParseWarning
+}
+
+// Segment is a single resource referenced by a playlist
+type Segment struct {
+	URL string
+	// Playlist is the URL of the media playlist the segment was listed in
+	Playlist  string
+	ByteRange *ByteRange
+	// Duration is the media duration of the segment from EXTINF, zero for keys,
+	// initialization segments and segments listed without one
+	Duration time.Duration
+	// Start is the segment's offset from the start of its media playlist, the sum
+	// of the durations of the media segments before it
+	Start time.Duration
+	// SequenceNumber is the media sequence number of a media segment; it is only
+	// meaningful when HasSequence is set, i.e. the playlist carries EXT-X-MEDIA-SEQUENCE
+	SequenceNumber int64
+	HasSequence    bool
+	// Discontinuity is the discontinuity sequence number of the segment: the
+	// playlist's EXT-X-DISCONTINUITY-SEQUENCE plus the EXT-X-DISCONTINUITY tags before it
+	Discontinuity int64
+	IsKey         bool
+	IsInit        bool
+}
+
+// ByteRange identifies a sub-range of a resource
+type ByteRange struct {
+	Length int64
+	Offset int64
+}
+
+// Variant describes a media playlist referenced by a master playlist
+type Variant struct {
+	URL string `json:"url"`
+	// Bandwidth is the variant's peak bit rate, 0 for renditions and when unknown
+	Bandwidth int64 `json:"bandwidth,omitempty"`
+	// IFrame marks an EXT-X-I-FRAME-STREAM-INF playlist used for trick play
+	IFrame bool `json:"iframe,omitempty"`
+	// Media is the TYPE of an EXT-X-MEDIA rendition playlist, e.g. SUBTITLES
+	Media    string `json:"media,omitempty"`
+	Segments int    `json:"segments"`
+}
+
+// parseM3U8 parses an M3U8 playlist and returns its segment URLs, descending into
+// variant and rendition playlists when given a master playlist
+func (h *HLSWarmer) parseM3U8(ctx context.Context, stream Stream) (*Playlist, error) {
+	playlist := &Playlist{}
+	visited := make(map[string]bool)
+	if err := h.parsePlaylist(ctx, stream, stream.URL, playlist, visited, 0); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// parsePlaylist downloads a single playlist and appends its segments to the given
+// playlist. Variant playlists are parsed recursively; visited guards against loops.
+func (h *HLSWarmer) parsePlaylist(ctx context.Context, stream Stream, m3u8URL string, playlist *Playlist, visited map[string]bool, depth int) error {
+	if depth > maxPlaylistDepth {
+		return fmt.Errorf("playlist nesting exceeds %d levels at %s", maxPlaylistDepth, m3u8URL)
+	}
+	visited[m3u8URL] = true
+
+	body, contentType, notModified, err := h.fetchManifest(ctx, stream, m3u8URL)
+	if err != nil {
+		return err
+	}
+
+	// An unchanged media playlist lists the same segments as last time
+	if notModified {
+		if parsed := h.parsedManifest(m3u8URL); parsed != nil {
+			h.log.Debug("Playlist unchanged, reusing its parsed segments", "playlist", m3u8URL)
+			*playlist = *parsed
+			return nil
+		}
+	}
+
+	// Hand DASH manifests served without an .mpd extension to the MPD parser
+	if depth == 0 && isMPDResponse(contentType, body) {
+		return parseMPDBody(h.resolveBase(m3u8URL), body, playlist, time.Now())
+	}
+
+	// A directory URL stands for the playlists it lists or a well-known index. Any
+	// other HTML page, such as a CDN error page, is reported below instead.
+	if depth == 0 && isDirectoryURL(m3u8URL) && !isM3U8Body(body) {
+		children, err := h.discoverPlaylists(ctx, stream, m3u8URL, body)
+		if err != nil {
+			return err
+		}
+		return h.parseChildren(ctx, stream, children, playlist, visited, depth)
+	}
+
+	// Refuse anything else rather than report a playlist without segments
+	if !isM3U8Body(body) {
+		return notPlaylistError(m3u8URL, contentType, body)
+	}
+
+	var segments []Segment
+	var variants []streamVariant
+	var renditions []rendition
+	var iframes []streamVariant
+	seen := make(map[string]bool)
+	ended := false
+
+	// Media segments are numbered from EXT-X-MEDIA-SEQUENCE when the playlist has one
+	var mediaSequence int64
+	hasSequence := false
+	mediaIndex := int64(0)
+	// Segments after each EXT-X-DISCONTINUITY belong to the next discontinuity sequence
+	var discontinuity int64
+	scanner := bufio.NewScanner(strings.NewReader(string(body)))
+
+	baseURL, err := url.Parse(h.resolveBase(m3u8URL))
+	if err != nil {
+		return err
+	}
+
+	// Parse M3U8 format; pendingVariant holds the EXT-X-STREAM-INF awaiting its URI
+	var pendingVariant *streamVariant
+
+	// Byte range state: a pending EXT-X-BYTERANGE applies to the next segment line,
+	// and ranges without an offset continue where the previous range of the same
+	// resource ended
+	var pendingRange string
+	// EXTINF gives the duration of the segment on the next URI line
+	var pendingDuration time.Duration
+	var elapsed time.Duration
+	var lastRangeURL string
+	var nextOffset int64
+
+	// Well-formedness: an #EXTINF or #EXT-X-STREAM-INF awaiting its URI is
+	// remembered by line number, and the playlist's own target duration bounds
+	// its segment durations
+	var warnings []ParseWarning
+	warn := func(line int, format string, args ...any) {
+		warnings = append(warnings, ParseWarning{Playlist: m3u8URL, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+	var lineNumber, extinfLine, variantLine int
+	var targetDuration time.Duration
+	hasTarget := false
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines
+		if line == "" {
+			continue
+		}
+
+		// Master playlist tags: variant URIs follow EXT-X-STREAM-INF on the next line,
+		// while renditions and I-frame playlists carry their URI as an attribute
+		if strings.HasPrefix(line, "#EXT-X-I-FRAME-STREAM-INF:") {
+			attrs := parseAttributes(line)
+			if uri := attrs["URI"]; uri != "" {
+				bandwidth, _ := strconv.ParseInt(attrs["BANDWIDTH"], 10, 64)
+				iframes = append(iframes, streamVariant{URL: resolveURL(baseURL, cleanURI(uri)), Bandwidth: bandwidth, IFrame: true})
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#EXT-X-STREAM-INF") {
+			if pendingVariant != nil {
+				warn(variantLine, "EXT-X-STREAM-INF is not followed by a playlist URI")
+			}
+			attrs := parseAttributes(line)
+			bandwidth, _ := strconv.ParseInt(attrs["BANDWIDTH"], 10, 64)
+			pendingVariant = &streamVariant{Bandwidth: bandwidth, Groups: variantGroups(attrs)}
+			variantLine = lineNumber
+			continue
+		}
+		if strings.HasPrefix(line, "#EXT-X-MEDIA:") {
+			attrs := parseAttributes(line)
+			if uri := attrs["URI"]; uri != "" {
+				kind := strings.ToUpper(attrs["TYPE"])
+				if h.mediaTypes != nil && !slices.Contains(h.mediaTypes, kind) {
+					h.log.Debug("Skipping rendition of unselected media type", "playlist", uri, "type", kind)
+					continue
+				}
+				renditions = append(renditions, rendition{
+					URL:   resolveURL(baseURL, cleanURI(uri)),
+					Type:  kind,
+					Group: kind + "/" + attrs["GROUP-ID"],
+				})
+			}
+			continue
+		}
+
+		// Encryption keys are warmed like segments, once per distinct URI
+		if strings.HasPrefix(line, "#EXT-X-KEY:") {
+			if uri := parseAttributes(line)["URI"]; uri != "" {
+				key := Segment{URL: resolveURL(baseURL, cleanURI(uri)), Playlist: m3u8URL, Discontinuity: discontinuity, IsKey: true}
+				if isHTTPURL(key.URL) && !seen[key.key()] {
+					seen[key.key()] = true
+					segments = append(segments, key)
+				}
+			}
+			continue
+		}
+
+		// Initialization segments for fMP4 streams, optionally a byte range of a file
+		if strings.HasPrefix(line, "#EXT-X-MAP:") {
+			attrs := parseAttributes(line)
+			if uri := attrs["URI"]; uri != "" {
+				init := Segment{URL: resolveURL(baseURL, cleanURI(uri)), Playlist: m3u8URL, Discontinuity: discontinuity, IsInit: true}
+				if value := attrs["BYTERANGE"]; value != "" {
+					byteRange, err := parseByteRange(value, 0)
+					if err != nil {
+						return fmt.Errorf("invalid EXT-X-MAP byte range %q: %v", value, err)
+					}
+					init.ByteRange = &byteRange
+				}
+				if !seen[init.key()] {
+					seen[init.key()] = true
+					segments = append(segments, init)
+				}
+			}
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "#EXT-X-TARGETDURATION:"); ok {
+			if seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && seconds > 0 {
+				targetDuration = time.Duration(seconds * float64(time.Second))
+				playlist.TargetDuration = max(playlist.TargetDuration, targetDuration)
+			} else {
+				warn(lineNumber, "invalid EXT-X-TARGETDURATION %q", value)
+			}
+			hasTarget = true
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"); ok {
+			sequence, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || sequence < 0 {
+				return fmt.Errorf("invalid EXT-X-MEDIA-SEQUENCE %q", value)
+			}
+			mediaSequence = sequence
+			hasSequence = true
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "#EXT-X-DISCONTINUITY-SEQUENCE:"); ok {
+			sequence, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || sequence < 0 {
+				return fmt.Errorf("invalid EXT-X-DISCONTINUITY-SEQUENCE %q", value)
+			}
+			discontinuity = sequence
+			continue
+		}
+
+		if line == "#EXT-X-DISCONTINUITY" {
+			discontinuity++
+			continue
+		}
+
+		if strings.HasPrefix(line, "#EXT-X-ENDLIST") {
+			ended = true
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "#EXTINF:"); ok {
+			if extinfLine > 0 {
+				warn(extinfLine, "EXTINF is not followed by a segment URI")
+			}
+			pendingDuration = parseExtinf(value)
+			if duration, _, _ := strings.Cut(value, ","); pendingDuration == 0 && strings.TrimSpace(duration) != "0" {
+				warn(lineNumber, "invalid EXTINF duration %q", duration)
+			}
+			extinfLine = lineNumber
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "#EXT-X-BYTERANGE:"); ok {
+			pendingRange = value
+			continue
+		}
+
+		// Skip other comments
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Clean the line to remove any control characters
+		cleanLine := cleanURI(line)
+
+		if pendingVariant != nil {
+			if cleanLine != "" {
+				pendingVariant.URL = resolveURL(baseURL, cleanLine)
+				variants = append(variants, *pendingVariant)
+			}
+			pendingVariant = nil
+			continue
+		}
+
+		// Validate that the line looks like a valid URL segment
+		if len(cleanLine) == 0 || strings.ContainsAny(cleanLine, "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x0b\x0c\x0e\x0f") {
+			continue // Skip invalid segments
+		}
+
+		if extinfLine == 0 {
+			warn(lineNumber, "segment URI has no EXTINF")
+		} else if targetDuration > 0 && pendingDuration.Round(time.Second) > targetDuration {
+			warn(extinfLine, "EXTINF duration %v exceeds EXT-X-TARGETDURATION %v", pendingDuration, targetDuration)
+		}
+		extinfLine = 0
+
+		// Resolve URL
+		segmentURL := resolveURL(baseURL, cleanLine)
+
+		// Validate the final URL
+		if parsedURL, err := url.Parse(segmentURL); err != nil || !parsedURL.IsAbs() {
+			continue // Skip URLs that can't be parsed or resolved
+		}
+
+		if !h.looksLikeSegment(cleanLine, segmentURL) {
+			h.log.Debug("Skipping line that does not look like a segment", "playlist", m3u8URL, "line", cleanLine)
+			continue
+		}
+
+		segment := Segment{URL: segmentURL, Playlist: m3u8URL, Duration: pendingDuration, Discontinuity: discontinuity}
+		segment.Start = elapsed
+		elapsed += pendingDuration
+		pendingDuration = 0
+		if hasSequence {
+			segment.SequenceNumber = mediaSequence + mediaIndex
+			segment.HasSequence = true
+		}
+		mediaIndex++
+		if pendingRange != "" {
+			defaultOffset := int64(0)
+			if segmentURL == lastRangeURL {
+				defaultOffset = nextOffset
+			}
+			byteRange, err := parseByteRange(pendingRange, defaultOffset)
+			if err != nil {
+				return fmt.Errorf("invalid EXT-X-BYTERANGE %q: %v", pendingRange, err)
+			}
+			segment.ByteRange = &byteRange
+			lastRangeURL = segmentURL
+			nextOffset = byteRange.Offset + byteRange.Length
+			pendingRange = ""
+		}
+
+		segments = append(segments, segment)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	master := len(variants) > 0 || len(renditions) > 0 || len(iframes) > 0
+	if extinfLine > 0 {
+		warn(extinfLine, "EXTINF is not followed by a segment URI")
+	}
+	if pendingVariant != nil {
+		warn(variantLine, "EXT-X-STREAM-INF is not followed by a playlist URI")
+	}
+	switch {
+	case master && countMedia(segments) > 0:
+		warn(0, "playlist mixes master playlist tags with media segments")
+	case !master && !hasTarget:
+		warn(0, "media playlist is missing the required EXT-X-TARGETDURATION tag")
+	}
+	if err := h.checkWarnings(warnings); err != nil {
+		return err
+	}
+	playlist.Warnings = append(playlist.Warnings, warnings...)
+
+	// Media playlist: collect its segments directly
+	if !master {
+		playlist.Segments = append(playlist.Segments, segments...)
+		playlist.Live = !ended
+		playlist.MediaSequence = mediaSequence
+		h.storeParsedManifest(m3u8URL, playlist)
+		return nil
+	}
+
+	// Master playlist: descend into the selected variants, the renditions they play
+	// with and the I-frame playlists used for trick play, skipping playlists seen already
+	if h.variant != "" && h.variant != VariantAll && len(variants) > 0 {
+		variants = selectVariants(variants, h.variant)
+		renditions = selectRenditions(renditions, variants)
+		iframes = selectVariants(iframes, h.variant)
+		h.log.Debug("Selected variants", "playlist", m3u8URL, "variant", h.variant, "variants", len(variants),
+			"renditions", len(renditions), "iframes", len(iframes))
+	}
+	children := slices.Concat(variants, iframes)
+	for _, r := range renditions {
+		children = append(children, streamVariant{URL: r.URL, Media: r.Type})
+	}
+
+	return h.parseChildren(ctx, stream, children, playlist, visited, depth)
+}
+
+// parseChildren parses the variant, rendition or discovered playlists of a parent
+// playlist and merges their segments into playlist
+func (h *HLSWarmer) parseChildren(ctx context.Context, stream Stream, children []streamVariant, playlist *Playlist, visited map[string]bool, depth int) error {
+	for _, child := range children {
+		variantURL := child.URL
+		if !h.hosts.allowed(variantURL) {
+			h.log.Debug("Skipping playlist on disallowed host", "playlist", variantURL)
+			playlist.Disallowed++
+			continue
+		}
+		if visited[variantURL] {
+			h.log.Debug("Skipping already visited playlist", "playlist", variantURL)
+			continue
+		}
+
+		variant := &Playlist{}
+		if err := h.parsePlaylist(ctx, stream, variantURL, variant, visited, depth+1); err != nil {
+			return fmt.Errorf("variant %s: %w", variantURL, err)
+		}
+
+		playlist.Segments = append(playlist.Segments, variant.Segments...)
+		playlist.Disallowed += variant.Disallowed
+		playlist.Warnings = append(playlist.Warnings, variant.Warnings...)
+		playlist.Live = playlist.Live || variant.Live
+		playlist.TargetDuration = max(playlist.TargetDuration, variant.TargetDuration)
+		if len(variant.Variants) > 0 {
+			playlist.Variants = append(playlist.Variants, variant.Variants...)
+		} else {
+			playlist.Variants = append(playlist.Variants, Variant{URL: variantURL, Bandwidth: child.Bandwidth, IFrame: child.IFrame, Media: child.Media, Segments: variant.mediaCount()})
+		}
+	}
+
+	return nil
+}
+
+// notPlaylistError explains why a manifest without the #EXTM3U header could not be
+// parsed, naming the kind of document it appears to be
+func notPlaylistError(m3u8URL, contentType string, body []byte) error {
+	trimmed := bytes.TrimSpace(body)
+	var kind string
+	switch {
+	case len(trimmed) == 0:
+		kind = "the response is empty"
+	case isMPDResponse(contentType, body):
+		kind = "it looks like a DASH manifest"
+	case bytes.Contains(trimmed, []byte("<SmoothStreamingMedia")) || strings.Contains(contentType, "vnd.ms-sstr+xml"):
+		kind = "it looks like a Smooth Streaming manifest, which is not supported"
+	case isHTMLResponse(contentType, body):
+		kind = "it looks like an HTML page"
+	case bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")):
+		kind = "it looks like a JSON document"
+	default:
+		firstLine, _, _ := bytes.Cut(trimmed, []byte("\n"))
+		kind = fmt.Sprintf("it starts with %q", truncate(cleanString(string(bytes.TrimSpace(firstLine))), 60))
+	}
+	return fmt.Errorf("%s is not an HLS playlist (no #EXTM3U header): %s", m3u8URL, kind)
+}
+
+// looksLikeSegment applies the segment-line heuristics: lines shorter than 5
+// characters are taken for markers, unless they are absolute URLs, absolute
+// paths or protocol-relative references, and URLs need a "." (e.g. a .ts or .m4s
+// extension) or one of the segment keywords. -no-segment-filter trusts every line.
+func (h *HLSWarmer) looksLikeSegment(line, segmentURL string) bool {
+	if h.noSegmentFilter {
+		return true
+	}
+	if len(line) < minSegmentLineLength && !strings.HasPrefix(line, "/") && !isHTTPURL(line) {
+		return false
+	}
+	if strings.Contains(segmentURL, ".") {
+		return true
+	}
+	return slices.ContainsFunc(h.segmentKeywords, func(keyword string) bool {
+		return strings.Contains(segmentURL, keyword)
+	})
+}
+
+// parseAttributes parses the attribute list of an M3U8 tag (e.g. KEY=value,URI="...")
+func parseAttributes(line string) map[string]string {
+	attrs := make(map[string]string)
+
+	// Drop the tag name
+	if i := strings.Index(line, ":"); i >= 0 {
+		line = line[i+1:]
+	} else {
+		return attrs
+	}
+
+	for len(line) > 0 {
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(line[:eq])
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, "\"") {
+			// Quoted values may contain commas
+			end := strings.Index(line[1:], "\"")
+			if end < 0 {
+				value = line[1:]
+				line = ""
+			} else {
+				value = line[1 : end+1]
+				line = line[end+2:]
+			}
+			line = strings.TrimPrefix(line, ",")
+		} else if comma := strings.Index(line, ","); comma >= 0 {
+			value = line[:comma]
+			line = line[comma+1:]
+		} else {
+			value = line
+			line = ""
+		}
+
+		attrs[key] = value
+	}
+
+	return attrs
+}
+
+// parseByteRange parses a "<length>[@<offset>]" byte range. When no offset is given
+// the range starts at defaultOffset.
+func parseByteRange(value string, defaultOffset int64) (ByteRange, error) {
+	lengthStr, offsetStr, hasOffset := strings.Cut(strings.TrimSpace(value), "@")
+
+	length, err := strconv.ParseInt(lengthStr, 10, 64)
+	if err != nil || length <= 0 {
+		return ByteRange{}, fmt.Errorf("invalid length %q", lengthStr)
+	}
+
+	offset := defaultOffset
+	if hasOffset {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || offset < 0 {
+			return ByteRange{}, fmt.Errorf("invalid offset %q", offsetStr)
+		}
+	}
+
+	return ByteRange{Length: length, Offset: offset}, nil
+}
+
+// header returns the value of the HTTP Range header for the byte range
+func (b ByteRange) header() string {
+	return fmt.Sprintf("bytes=%d-%d", b.Offset, b.Offset+b.Length-1)
+}
+
+// key returns the identity of a segment, distinguishing byte ranges of the same URL
+func (s Segment) key() string {
+	if s.ByteRange == nil {
+		return s.URL
+	}
+	return s.URL + "#" + s.ByteRange.header()
+}
+
+// trackingKey identifies a segment across playlist refreshes. Media segments with a
+// sequence number are tracked by playlist and sequence number, which survives
+// rotating query parameters and filenames reused across discontinuities; other
+// segments fall back to their URL and byte range.
+func (s Segment) trackingKey() string {
+	if !s.HasSequence {
+		return s.key()
+	}
+	return fmt.Sprintf("%s#seq=%d", s.Playlist, s.SequenceNumber)
+}
+
+// mediaCount returns the number of media segments, excluding keys and init segments
+func (p *Playlist) mediaCount() int {
+	return countMedia(p.Segments)
+}
+
+// clone returns a copy of the playlist that shares none of its slices
+func (p *Playlist) clone() *Playlist {
+	out := *p
+	out.Segments = slices.Clone(p.Segments)
+	out.Variants = slices.Clone(p.Variants)
+	out.Warnings = slices.Clone(p.Warnings)
+	return &out
+}
+
+// countMedia returns the number of media segments, excluding keys and init segments
+func countMedia(segments []Segment) int {
+	count := 0
+	for _, segment := range segments {
+		if !segment.IsKey && !segment.IsInit {
+			count++
+		}
+	}
+	return count
+}
+
+// parseExtinf returns the duration of an EXTINF value such as "4.004,title",
+// or zero when it is malformed
+func parseExtinf(value string) time.Duration {
+	value, _, _ = strings.Cut(value, ",")
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,310 @@
+package hlswarm
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer serves the given paths over TLS, each with its body and optional
+// headers; any other path is a 404
+func newTestServer(t *testing.T, files map[string]string, headers map[string]http.Header) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for key, values := range headers[r.URL.Path] {
+			w.Header()[key] = values
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestWarmer returns a warmer whose requests go through the test server's
+// transport, which trusts its self-signed certificate
+func newTestWarmer(srv *httptest.Server, config Config) *HLSWarmer {
+	config.Transport = srv.Client().Transport
+	config.LogLevel = slog.LevelError + 1
+	config.Quiet = true
+	return New(config)
+}
+
+func TestParseM3U8MediaPlaylist(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/live/index.m3u8": `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-KEY:METHOD=AES-128,URI="keys/k1.key"
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4.0,
+seg100.m4s
+#EXTINF:3.5,
+/abs/seg101.m4s?token=abc
+#EXT-X-KEY:METHOD=AES-128,URI="keys/k1.key"
+#EXTINF:4.0,
+seg102.m4s
+`,
+	}, nil)
+	h := newTestWarmer(srv, Config{})
+
+	playlist, err := h.parseM3U8(context.Background(), Stream{URL: srv.URL + "/live/index.m3u8"})
+	if err != nil {
+		t.Fatalf("parseM3U8: %v", err)
+	}
+
+	want := []Segment{
+		{URL: srv.URL + "/live/keys/k1.key", IsKey: true},
+		{URL: srv.URL + "/live/init.mp4", IsInit: true},
+		{URL: srv.URL + "/live/seg100.m4s", Duration: 4 * time.Second, SequenceNumber: 100},
+		{URL: srv.URL + "/abs/seg101.m4s?token=abc", Duration: 3500 * time.Millisecond, SequenceNumber: 101},
+		{URL: srv.URL + "/live/seg102.m4s", Duration: 4 * time.Second, SequenceNumber: 102},
+	}
+	if len(playlist.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %d: %+v", len(playlist.Segments), len(want), playlist.Segments)
+	}
+	for i, w := range want {
+		got := playlist.Segments[i]
+		if got.URL != w.URL || got.IsKey != w.IsKey || got.IsInit != w.IsInit || got.Duration != w.Duration || got.SequenceNumber != w.SequenceNumber {
+			t.Errorf("segment %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if !playlist.Live {
+		t.Error("playlist without EXT-X-ENDLIST is not live")
+	}
+	if playlist.TargetDuration != 4*time.Second {
+		t.Errorf("TargetDuration = %v, want 4s", playlist.TargetDuration)
+	}
+	if playlist.MediaSequence != 100 {
+		t.Errorf("MediaSequence = %d, want 100", playlist.MediaSequence)
+	}
+	if len(playlist.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", playlist.Warnings)
+	}
+}
+
+func TestParseM3U8ByteRanges(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/vod.m3u8": `#EXTM3U
+#EXT-X-TARGETDURATION:2
+#EXT-X-MAP:URI="main.mp4",BYTERANGE="720@0"
+#EXTINF:2.0,
+#EXT-X-BYTERANGE:1000@720
+main.mp4
+#EXTINF:2.0,
+#EXT-X-BYTERANGE:500
+main.mp4
+#EXT-X-ENDLIST
+`,
+	}, nil)
+	h := newTestWarmer(srv, Config{})
+
+	playlist, err := h.parseM3U8(context.Background(), Stream{URL: srv.URL + "/vod.m3u8"})
+	if err != nil {
+		t.Fatalf("parseM3U8: %v", err)
+	}
+
+	want := []ByteRange{{Length: 720, Offset: 0}, {Length: 1000, Offset: 720}, {Length: 500, Offset: 1720}}
+	if len(playlist.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(playlist.Segments), len(want))
+	}
+	for i, w := range want {
+		got := playlist.Segments[i]
+		if got.URL != srv.URL+"/main.mp4" || got.ByteRange == nil || *got.ByteRange != w {
+			t.Errorf("segment %d = %s %+v, want range %+v", i, got.URL, got.ByteRange, w)
+		}
+	}
+	if playlist.Live {
+		t.Error("playlist with EXT-X-ENDLIST is live")
+	}
+}
+
+func TestParseM3U8MasterPlaylist(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/master.m3u8": `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="en",URI="audio/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=800000,AUDIO="aac"
+low/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2400000,AUDIO="aac"
+high/index.m3u8
+`,
+		"/low/index.m3u8":  "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\nseg1.ts\n#EXTINF:4,\nseg2.ts\n#EXT-X-ENDLIST\n",
+		"/high/index.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\nseg1.ts\n#EXT-X-ENDLIST\n",
+		"/audio/en.m3u8":   "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\naudio1.aac\n#EXT-X-ENDLIST\n",
+	}, nil)
+
+	tests := []struct {
+		variant  string
+		segments []string
+		variants int
+	}{
+		{VariantAll, []string{"/low/seg1.ts", "/low/seg2.ts", "/high/seg1.ts", "/audio/audio1.aac"}, 3},
+		{VariantLowest, []string{"/low/seg1.ts", "/low/seg2.ts", "/audio/audio1.aac"}, 2},
+		{VariantHighest, []string{"/high/seg1.ts", "/audio/audio1.aac"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.variant, func(t *testing.T) {
+			h := newTestWarmer(srv, Config{Variant: tt.variant})
+			playlist, err := h.parseM3U8(context.Background(), Stream{URL: srv.URL + "/master.m3u8"})
+			if err != nil {
+				t.Fatalf("parseM3U8: %v", err)
+			}
+			var got []string
+			for _, segment := range playlist.Segments {
+				got = append(got, strings.TrimPrefix(segment.URL, srv.URL))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.segments, " ") {
+				t.Errorf("segments = %v, want %v", got, tt.segments)
+			}
+			if len(playlist.Variants) != tt.variants {
+				t.Errorf("got %d variants, want %d: %+v", len(playlist.Variants), tt.variants, playlist.Variants)
+			}
+		})
+	}
+}
+
+func TestParseM3U8Errors(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/page.m3u8":  "<!DOCTYPE html><html><body>Not here</body></html>",
+		"/empty.m3u8": "",
+		"/smooth.m3u8": `<?xml version="1.0"?>
+<SmoothStreamingMedia MajorVersion="2"></SmoothStreamingMedia>`,
+	}, nil)
+	h := newTestWarmer(srv, Config{})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/missing.m3u8", "HTTP 404"},
+		{"/page.m3u8", "HTML page"},
+		{"/empty.m3u8", "empty"},
+		{"/smooth.m3u8", "Smooth Streaming"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := h.parseM3U8(context.Background(), Stream{URL: srv.URL + tt.path})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseM3U8 error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseM3U8Warnings(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/bad.m3u8": "#EXTM3U\n#EXTINF:4,\n#EXTINF:4,\nseg1.ts\nseg2.ts\n#EXT-X-ENDLIST\n",
+	}, nil)
+	stream := Stream{URL: srv.URL + "/bad.m3u8"}
+
+	playlist, err := newTestWarmer(srv, Config{}).parseM3U8(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("parseM3U8: %v", err)
+	}
+	want := []ParseWarning{
+		{Playlist: stream.URL, Line: 2, Message: "EXTINF is not followed by a segment URI"},
+		{Playlist: stream.URL, Line: 5, Message: "segment URI has no EXTINF"},
+		{Playlist: stream.URL, Message: "media playlist is missing the required EXT-X-TARGETDURATION tag"},
+	}
+	if len(playlist.Warnings) != len(want) {
+		t.Fatalf("warnings = %v, want %v", playlist.Warnings, want)
+	}
+	for i, w := range want {
+		if playlist.Warnings[i] != w {
+			t.Errorf("warning %d = %v, want %v", i, playlist.Warnings[i], w)
+		}
+	}
+
+	_, err = newTestWarmer(srv, Config{Strict: true}).parseM3U8(context.Background(), stream)
+	if err == nil || !strings.Contains(err.Error(), "malformed playlist") {
+		t.Errorf("strict parseM3U8 error = %v, want a malformed playlist error", err)
+	}
+}
+
+func TestLooksLikeSegment(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		line       string
+		segmentURL string
+		want       bool
+	}{
+		{"relative with extension", Config{}, "seg1.ts", "https://cdn.example.com/live/seg1.ts", true},
+		{"absolute path with query", Config{}, "/v1/seg10.ts?token=abc", "https://cdn.example.com/v1/seg10.ts?token=abc", true},
+		{"protocol-relative", Config{}, "//edge.example.com/seg.ts", "https://edge.example.com/seg.ts", true},
+		{"full URL", Config{}, "https://edge.example.com/seg.ts", "https://edge.example.com/seg.ts", true},
+		{"short marker", Config{}, "ab", "http://localhost/ab", false},
+		{"short absolute path with keyword", Config{}, "/s1", "http://localhost/seg/s1", true},
+		{"short absolute path without keyword", Config{}, "/a", "http://localhost/a", false},
+		{"keyword without extension", Config{}, "chunk42", "http://localhost/chunk42", true},
+		{"neither extension nor keyword", Config{}, "media42", "http://localhost/media42", false},
+		{"absolute path needs a custom keyword", Config{}, "/v1/part7", "http://localhost/v1/part7", false},
+		{"custom keyword", Config{SegmentKeywords: []string{"part"}}, "/v1/part7", "http://localhost/v1/part7", true},
+		{"filter disabled", Config{NoSegmentFilter: true}, "ab", "http://localhost/ab", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.config)
+			if got := h.looksLikeSegment(tt.line, tt.segmentURL); got != tt.want {
+				t.Errorf("looksLikeSegment(%q, %q) = %v, want %v", tt.line, tt.segmentURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseM3U8ProtocolRelativeSegments(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"/index.m3u8": "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\n//cdn.example.com/seg1.ts\n#EXTINF:4,\n///seg2.ts\n#EXT-X-ENDLIST\n",
+	}, nil)
+	h := newTestWarmer(srv, Config{})
+
+	playlist, err := h.parseM3U8(context.Background(), Stream{URL: srv.URL + "/index.m3u8"})
+	if err != nil {
+		t.Fatalf("parseM3U8: %v", err)
+	}
+	if len(playlist.Segments) != 1 || playlist.Segments[0].URL != "https://cdn.example.com/seg1.ts" {
+		t.Errorf("segments = %+v, want only https://cdn.example.com/seg1.ts", playlist.Segments)
+	}
+}
+
+func TestParseM3U8NotModifiedReusesPlaylist(t *testing.T) {
+	notModified := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\nseg1.ts\n#EXTINF:4,\nseg2.ts\n"))
+	}))
+	t.Cleanup(srv.Close)
+	h := newTestWarmer(srv, Config{})
+	stream := Stream{URL: srv.URL + "/index.m3u8"}
+
+	first, err := h.parseM3U8(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("parseM3U8: %v", err)
+	}
+	first.Segments[0].URL = "changed by the caller"
+
+	second, err := h.parseM3U8(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("parseM3U8 after 304: %v", err)
+	}
+	if notModified != 1 {
+		t.Fatalf("server answered %d conditional requests with 304, want 1", notModified)
+	}
+	if len(second.Segments) != 2 || second.Segments[0].URL != srv.URL+"/seg1.ts" || !second.Live {
+		t.Errorf("reused playlist = %+v, want the live playlist parsed first", second)
+	}
+}
@@ -0,0 +1,170 @@
+package hlswarm
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prediction is given up for a stream once this many predicted requests found
+// fewer than minPredictionHitRatio of their segments
+const (
+	minPredictionAttempts = 30
+	minPredictionHitRatio = 0.2
+)
+
+// predictionStats tracks how often a stream's predicted segments existed
+type predictionStats struct {
+	attempts int
+	found    int
+	disabled bool
+}
+
+// warmPredicted speculatively warms the next -predict-ahead segments of each
+// media playlist, extrapolated from the numbered filename of its newest segment.
+// Segments that exist are marked processed so they are not warmed again when
+// they appear in the playlist. It returns how many predictions were found and
+// how many were not.
+func (h *HLSWarmer) warmPredicted(ctx context.Context, stream Stream, segments []Segment) (found, notFound int) {
+	m3u8URL := stream.URL
+
+	h.streamMu.Lock()
+	stats, ok := h.predictions[m3u8URL]
+	if !ok {
+		stats = &predictionStats{}
+		h.predictions[m3u8URL] = stats
+	}
+	disabled := stats.disabled
+	h.streamMu.Unlock()
+	if disabled {
+		return 0, 0
+	}
+
+	var pending []Segment
+	h.mu.RLock()
+	for _, segment := range predictSegments(segments, h.predictAhead) {
+		last, seen := h.processedURLs[h.dedupKey(segment)]
+		if !seen || time.Since(last) > stream.TTL {
+			pending = append(pending, segment)
+		}
+	}
+	h.mu.RUnlock()
+	if len(pending) == 0 {
+		return 0, 0
+	}
+
+	// Results come back in completion order, so match them to segments by URL
+	byURL := make(map[string]Segment, len(pending))
+	for _, segment := range pending {
+		byURL[segment.URL] = segment
+	}
+
+	results := h.warmSegments(ctx, stream, pending)
+
+	h.mu.Lock()
+	for _, r := range results {
+		if r.Error != nil || r.StatusCode < 200 || r.StatusCode >= 300 {
+			notFound++
+			continue
+		}
+		found++
+		h.processedURLs[h.dedupKey(byURL[r.URL])] = time.Now()
+	}
+	h.mu.Unlock()
+
+	h.log.Info("Predicted segments", Icon("🔮"), "stream", m3u8URL, "found", found, "not_found", notFound)
+
+	h.streamMu.Lock()
+	stats.attempts += found + notFound
+	stats.found += found
+	if stats.attempts >= minPredictionAttempts && float64(stats.found)/float64(stats.attempts) < minPredictionHitRatio {
+		stats.disabled = true
+	}
+	disabled = stats.disabled
+	h.streamMu.Unlock()
+	if disabled {
+		h.log.Warn("Prediction rarely finds segments, disabling it for stream", "stream", m3u8URL,
+			"found", stats.found, "attempts", stats.attempts)
+	}
+	return found, notFound
+}
+
+// predictSegments extrapolates the next n media segments of each media playlist
+// by incrementing the number in the newest segment's filename, e.g. segment_1234.ts
+// becomes segment_1235.ts. Playlists whose segments are not numbered are skipped.
+func predictSegments(segments []Segment, n int) []Segment {
+	newest := make(map[string]Segment)
+	var playlists []string
+	for _, segment := range segments {
+		if segment.IsKey || segment.IsInit || segment.ByteRange != nil {
+			continue
+		}
+		if _, ok := newest[segment.Playlist]; !ok {
+			playlists = append(playlists, segment.Playlist)
+		}
+		newest[segment.Playlist] = segment
+	}
+
+	var predicted []Segment
+	for _, playlist := range playlists {
+		last := newest[playlist]
+		for step := 1; step <= n; step++ {
+			next, ok := nextSegmentURL(last.URL, step)
+			if !ok {
+				break
+			}
+			segment := Segment{
+				URL:           next,
+				Playlist:      playlist,
+				Duration:      last.Duration,
+				Discontinuity: last.Discontinuity,
+			}
+			if last.HasSequence {
+				segment.SequenceNumber = last.SequenceNumber + int64(step)
+				segment.HasSequence = true
+			}
+			predicted = append(predicted, segment)
+		}
+	}
+	return predicted
+}
+
+// nextSegmentURL adds step to the last run of digits in the URL's filename,
+// keeping any zero padding. It reports false when the filename has no number.
+func nextSegmentURL(rawURL string, step int) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	dir, file := "", parsed.Path
+	if i := strings.LastIndex(parsed.Path, "/"); i >= 0 {
+		dir, file = parsed.Path[:i+1], parsed.Path[i+1:]
+	}
+
+	end := strings.LastIndexFunc(file, isDigit) + 1
+	if end == 0 {
+		return "", false
+	}
+	start := strings.LastIndexFunc(file[:end], func(r rune) bool { return !isDigit(r) }) + 1
+
+	number, err := strconv.ParseUint(file[start:end], 10, 63)
+	if err != nil {
+		return "", false
+	}
+	digits := strconv.FormatUint(number+uint64(step), 10)
+	if pad := end - start - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+
+	parsed.Path = dir + file[:start] + digits + file[end:]
+	parsed.RawPath = ""
+	return parsed.String(), true
+}
+
+// isDigit reports whether r is an ASCII digit
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
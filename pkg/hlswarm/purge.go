@@ -0,0 +1,126 @@
+package hlswarm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPurgeMethod is the HTTP method of -purge requests, understood by
+// Varnish, Fastly, nginx cache_purge and others
+const DefaultPurgeMethod = "PURGE"
+
+// Purge summarizes the purge requests sent before warming in -purge mode
+type Purge struct {
+	// Purged counts 2xx responses, NotCached 404s, which most caches return when
+	// there was nothing to purge, and Failed errors and any other status
+	Purged    int
+	NotCached int
+	Failed    int
+	Details   []PurgedSegment
+}
+
+// PurgedSegment is the outcome of the purge request for one segment and edge
+type PurgedSegment struct {
+	URL        string
+	Edge       string
+	StatusCode int
+	Error      error
+	Duration   time.Duration
+}
+
+// purgeSegments sends a purge request for every segment, once per edge, before
+// they are warmed so that the warm fetches fresh content from the origin
+func (h *HLSWarmer) purgeSegments(ctx context.Context, stream Stream, segments []Segment) *Purge {
+	edges := h.edgeIPs
+	if len(edges) == 0 {
+		edges = []string{""}
+	}
+
+	// Byte ranges of one resource share a cache entry, so purge each URL once
+	var urls []string
+	seen := make(map[string]bool)
+	for _, segment := range segments {
+		if !seen[segment.URL] {
+			seen[segment.URL] = true
+			urls = append(urls, segment.URL)
+		}
+	}
+
+	h.log.Info("Purging segments", Icon("🧹"), "stream", stream.URL, "segments", len(urls), "method", h.purgeMethod)
+	purge := &Purge{Details: make([]PurgedSegment, len(urls)*len(edges))}
+	sem := make(chan struct{}, h.maxWorkers)
+	var wg sync.WaitGroup
+	for i, segmentURL := range urls {
+		for j, edge := range edges {
+			sem <- struct{}{}
+			wg.Go(func() {
+				defer func() { <-sem }()
+				purge.Details[i*len(edges)+j] = h.purgeSegment(withEdge(ctx, edge), segmentURL)
+			})
+		}
+	}
+	wg.Wait()
+
+	for _, p := range purge.Details {
+		switch {
+		case p.Error == nil && p.StatusCode >= 200 && p.StatusCode < 300:
+			purge.Purged++
+		case p.Error == nil && p.StatusCode == http.StatusNotFound:
+			purge.NotCached++
+		default:
+			purge.Failed++
+		}
+	}
+
+	h.log.Info("Purged segments", Icon("🧹"), "stream", stream.URL, "purged", purge.Purged,
+		"not_cached", purge.NotCached, "failed", purge.Failed)
+	return purge
+}
+
+// purgeSegment sends the purge request for a segment. It carries only the -purge-header
+// headers, such as the CDN's API token, and the custom Host header, if any.
+func (h *HLSWarmer) purgeSegment(ctx context.Context, segmentURL string) PurgedSegment {
+	startTime := time.Now()
+	purged := PurgedSegment{URL: segmentURL, Edge: edgeFromContext(ctx)}
+
+	if h.limiter != nil {
+		if err := h.limiter.Wait(ctx); err != nil {
+			purged.Error = err
+			return purged
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, h.purgeMethod, segmentURL, nil)
+	if err != nil {
+		purged.Error = err
+		return purged
+	}
+	req.Header.Set("User-Agent", h.nextUserAgent())
+	for key, value := range h.headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+		}
+	}
+	for key, value := range h.purgeHeaders {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.clientFor(ctx).Do(req)
+	purged.Duration = time.Since(startTime)
+	if err != nil {
+		purged.Error = fmt.Errorf("%s", cleanString(err.Error()))
+		return purged
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	purged.StatusCode = resp.StatusCode
+	return purged
+}
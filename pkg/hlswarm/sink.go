@@ -0,0 +1,113 @@
+package hlswarm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResultSink receives warm results. Every configured sink sees every result, so
+// several output formats can be written at once.
+type ResultSink interface {
+	// Record receives the result of warming a whole playlist in one-shot mode
+	Record(result *WarmResult)
+	// RecordCycle receives the result of one daemon cycle over a stream's new segments
+	RecordCycle(result *WarmResult)
+}
+
+// newSinks creates the sinks for the configured output formats followed by any
+// custom sinks. The console sink always runs so daemon cycles are summarized in
+// the log, but it only prints full one-shot reports for the text format.
+func newSinks(h *HLSWarmer, outputs []string, config Config) []ResultSink {
+	sinks := []ResultSink{&consoleSink{h: h, report: len(outputs) == 0 || slices.Contains(outputs, OutputText)}}
+	if slices.Contains(outputs, OutputJSON) {
+		sinks = append(sinks, &jsonSink{h: h})
+	}
+	if slices.Contains(outputs, OutputCSV) && config.CSVReport != nil {
+		sinks = append(sinks, &csvSink{h: h, report: config.CSVReport})
+	}
+	return append(sinks, config.Sinks...)
+}
+
+// consoleSink prints human-readable reports and logs daemon cycle summaries
+type consoleSink struct {
+	h      *HLSWarmer
+	report bool
+}
+
+func (s *consoleSink) Record(result *WarmResult) {
+	if !s.report {
+		return
+	}
+	s.h.PrintResults(result)
+	fmt.Fprintln(s.h.out, "\n"+strings.Repeat("=", 50))
+}
+
+func (s *consoleSink) RecordCycle(result *WarmResult) {
+	h := s.h
+	m3u8URL := result.M3U8URL
+
+	errorCount := 0
+	var wireBytes int64
+	for _, r := range result.Details {
+		wireBytes += max(r.WireBytes, 0)
+		if r.Error != nil {
+			errorCount++
+		}
+		if r.Suspicious != "" {
+			h.log.Warn("Suspicious segment response", Icon("🚩"), "stream", m3u8URL, "segment", r.URL, "reason", r.Suspicious)
+		}
+	}
+
+	h.log.Info("Stream cycle complete", Icon("📊"), "stream", m3u8URL, "segments", result.TotalFiles,
+		"hits", result.CachedFiles, "errors", errorCount, "bytes", formatBytes(wireBytes), "content", result.TotalDuration, "duration", result.Duration,
+		"p50", result.P50.Round(time.Millisecond), "p95", result.P95.Round(time.Millisecond), "p99", result.P99.Round(time.Millisecond),
+		"conn_reuse", fmt.Sprintf("%.0f%%", result.connReuseRatio()*100))
+
+	if len(h.edgeIPs) > 0 {
+		for _, edge := range groupResults(result.Details, func(r CacheStatus) string { return r.Edge }) {
+			h.log.Info("Edge cycle complete", Icon("🌍"), "stream", m3u8URL, "edge", edge.name,
+				"segments", edge.total, "hits", edge.hits, "errors", edge.errors)
+		}
+	}
+
+	if len(h.streamFor(m3u8URL).Mirrors) > 0 {
+		for _, mirror := range groupResults(result.Details, func(r CacheStatus) string { return r.Mirror }) {
+			h.log.Info("Mirror cycle complete", Icon("🪞"), "stream", m3u8URL, "mirror", mirror.name,
+				"segments", mirror.total, "hits", mirror.hits, "errors", mirror.errors)
+		}
+	}
+
+	// Show error details in quiet mode if there are errors
+	if h.quiet {
+		for _, r := range result.Details {
+			if r.Error != nil {
+				// Sanitize error messages to prevent terminal corruption
+				h.log.Warn("Segment error", "stream", m3u8URL, "segment", r.URL, "error", cleanString(r.Error.Error()))
+			}
+		}
+	}
+}
+
+// jsonSink writes every result as a line of JSON to stdout
+type jsonSink struct {
+	h *HLSWarmer
+}
+
+func (s *jsonSink) Record(result *WarmResult)      { s.h.PrintJSON(result) }
+func (s *jsonSink) RecordCycle(result *WarmResult) { s.h.PrintJSON(result) }
+
+// csvSink appends every result's segments to a CSV report
+type csvSink struct {
+	h      *HLSWarmer
+	report *CSVReport
+}
+
+func (s *csvSink) Record(result *WarmResult) { s.RecordCycle(result) }
+
+func (s *csvSink) RecordCycle(result *WarmResult) {
+	if err := s.report.write(result); err != nil {
+		s.h.log.Error("Error writing CSV report", "error", err)
+	}
+}
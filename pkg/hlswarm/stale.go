@@ -0,0 +1,39 @@
+package hlswarm
+
+// recordFreshSegments tracks how many consecutive daemon cycles of a stream found
+// no segments it had never seen before. Once -stale-cycles is reached the stream
+// is reported as stale, telling an origin whose live playlist stopped updating
+// apart from one that is merely idle between segments.
+func (h *HLSWarmer) recordFreshSegments(m3u8URL string, fresh int) {
+	if h.staleCycles <= 0 {
+		return
+	}
+
+	h.streamMu.Lock()
+	wasStale := h.streamIdle[m3u8URL] >= h.staleCycles
+	if fresh > 0 {
+		delete(h.streamIdle, m3u8URL)
+	} else {
+		h.streamIdle[m3u8URL]++
+	}
+	idle := h.streamIdle[m3u8URL]
+	h.streamMu.Unlock()
+
+	switch {
+	case fresh > 0 && wasStale:
+		h.metrics.observeStale(m3u8URL, false)
+		h.log.Info("Stream updating again", Icon("🌊"), "stream", m3u8URL)
+	case idle == h.staleCycles:
+		h.metrics.observeStale(m3u8URL, true)
+		h.log.Warn("Stream stale, playlist has not changed", Icon("🧊"), "stream", m3u8URL, "cycles", idle)
+	}
+}
+
+// streamIdleCycles returns the number of consecutive cycles a stream found no new
+// segments, and whether that reaches -stale-cycles
+func (h *HLSWarmer) streamIdleCycles(m3u8URL string) (int, bool) {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	idle := h.streamIdle[m3u8URL]
+	return idle, h.staleCycles > 0 && idle >= h.staleCycles
+}
@@ -0,0 +1,146 @@
+package hlswarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultStateSaveInterval is how often the daemon writes its state file
+const defaultStateSaveInterval = 30 * time.Second
+
+// stateFile is the on-disk form of the daemon's processed-segment state
+type stateFile struct {
+	Processed map[string]time.Time `json:"processed"`
+}
+
+// loadState restores processed segments from the state file, dropping entries
+// that are already older than every configured TTL. A missing file is not an error.
+func (h *HLSWarmer) loadState() error {
+	data, err := os.ReadFile(h.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("invalid state file %s: %v", h.stateFile, err)
+	}
+
+	ttl := h.maxTTL()
+	loaded := 0
+	h.mu.Lock()
+	for key, last := range state.Processed {
+		if time.Since(last) > ttl {
+			continue
+		}
+		h.processedURLs[key] = last
+		loaded++
+	}
+	h.mu.Unlock()
+
+	h.log.Info("Loaded processed segments", Icon("💾"), "count", loaded, "file", h.stateFile)
+	return nil
+}
+
+// saveState writes processed segments to the state file. The file is replaced
+// atomically so a crash mid-write never leaves a truncated state behind.
+func (h *HLSWarmer) saveState() error {
+	ttl := h.maxTTL()
+	state := stateFile{Processed: make(map[string]time.Time)}
+	h.mu.RLock()
+	for key, last := range h.processedURLs {
+		if time.Since(last) <= ttl {
+			state.Processed[key] = last
+		}
+	}
+	h.mu.RUnlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.stateFile), filepath.Base(h.stateFile)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), h.stateFile)
+}
+
+// PruneState drops the entries of the state file that are older than every
+// configured TTL and writes it back, without warming anything. It returns how
+// many entries were kept and pruned.
+func (h *HLSWarmer) PruneState() (kept, pruned int, err error) {
+	data, err := os.ReadFile(h.stateFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, 0, fmt.Errorf("invalid state file %s: %v", h.stateFile, err)
+	}
+
+	ttl := h.maxTTL()
+	h.mu.Lock()
+	for key, last := range state.Processed {
+		if time.Since(last) > ttl {
+			pruned++
+			continue
+		}
+		h.processedURLs[key] = last
+		kept++
+	}
+	h.mu.Unlock()
+
+	return kept, pruned, h.saveState()
+}
+
+// persistState saves the state file periodically until ctx is cancelled, then
+// saves it one final time
+func (h *HLSWarmer) persistState(ctx context.Context) {
+	ticker := time.NewTicker(defaultStateSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := h.saveState(); err != nil {
+				h.log.Warn("Error saving state", "file", h.stateFile, "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := h.saveState(); err != nil {
+				h.log.Warn("Error saving state", "file", h.stateFile, "error", err)
+			}
+		}
+	}
+}
+
+// maxTTL returns the longest TTL of any stream, beyond which no processed entry matters
+func (h *HLSWarmer) maxTTL() time.Duration {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+
+	ttl := h.processedTTL
+	for _, stream := range h.streams {
+		ttl = max(ttl, stream.TTL)
+	}
+	return ttl
+}
@@ -0,0 +1,145 @@
+package hlswarm
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// statsHistory is the number of recent cycle hit ratios kept per stream
+const statsHistory = 10
+
+// StreamStats aggregates a daemon stream's results across warm cycles
+type StreamStats struct {
+	Cycles int `json:"cycles"`
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+	Errors int `json:"errors"`
+	// Bytes is the number of bytes transferred across all cycles
+	Bytes int64 `json:"bytes"`
+	// RecentRatios holds the hit ratio (0-1) of the most recent cycles, oldest first
+	RecentRatios []float64 `json:"recent_ratios"`
+}
+
+// percentile returns the nearest-rank p-th percentile (0-100) of sorted durations,
+// or 0 when there are none
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[max((len(sorted)*p+99)/100-1, 0)]
+}
+
+// HitRatio returns the overall hit ratio across all cycles
+func (s StreamStats) HitRatio() float64 {
+	total := s.Hits + s.Misses + s.Errors
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// trend describes how the hit ratio moved over the recent cycles, e.g.
+// "hit ratio 62% → 88% over last 10 cycles"
+func (s StreamStats) trend() string {
+	if len(s.RecentRatios) < 2 {
+		return ""
+	}
+	first, last := s.RecentRatios[0], s.RecentRatios[len(s.RecentRatios)-1]
+	return fmt.Sprintf("hit ratio %.0f%% → %.0f%% over last %d cycles", first*100, last*100, len(s.RecentRatios))
+}
+
+// StreamStats returns the aggregated statistics of a daemon stream
+func (h *HLSWarmer) StreamStats(m3u8URL string) StreamStats {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+
+	stats, ok := h.streamStats[m3u8URL]
+	if !ok {
+		return StreamStats{}
+	}
+	out := *stats
+	out.RecentRatios = slices.Clone(stats.RecentRatios)
+	return out
+}
+
+// recordStreamStats adds a cycle's results to the stream's statistics and returns
+// the updated statistics
+func (h *HLSWarmer) recordStreamStats(m3u8URL string, results []CacheStatus) StreamStats {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+
+	stats, ok := h.streamStats[m3u8URL]
+	if !ok {
+		stats = &StreamStats{}
+		h.streamStats[m3u8URL] = stats
+	}
+
+	hits := 0
+	for _, r := range results {
+		stats.Bytes += max(r.WireBytes, 0)
+		switch {
+		case r.Error != nil:
+			stats.Errors++
+		case r.Hit:
+			stats.Hits++
+			hits++
+		default:
+			stats.Misses++
+		}
+	}
+
+	stats.Cycles++
+	if len(results) > 0 {
+		stats.RecentRatios = append(stats.RecentRatios, float64(hits)/float64(len(results)))
+		if len(stats.RecentRatios) > statsHistory {
+			stats.RecentRatios = stats.RecentRatios[len(stats.RecentRatios)-statsHistory:]
+		}
+	}
+
+	out := *stats
+	out.RecentRatios = slices.Clone(stats.RecentRatios)
+	return out
+}
+
+// PrintDaemonSummary prints lifetime totals across every stream the daemon warmed,
+// followed by a per-stream breakdown
+func (h *HLSWarmer) PrintDaemonSummary(uptime time.Duration) {
+	h.streamMu.Lock()
+	urls := slices.Sorted(maps.Keys(h.streamStats))
+	streams := make([]StreamStats, 0, len(urls))
+	for _, m3u8URL := range urls {
+		streams = append(streams, *h.streamStats[m3u8URL])
+	}
+	h.streamMu.Unlock()
+
+	var total StreamStats
+	for _, stats := range streams {
+		total.Cycles += stats.Cycles
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Errors += stats.Errors
+		total.Bytes += stats.Bytes
+	}
+
+	fmt.Fprintf(h.out, "\n📊 DAEMON SUMMARY\n")
+	fmt.Fprintf(h.out, "==========================================\n")
+	fmt.Fprintf(h.out, "Uptime: %v\n", uptime.Round(time.Second))
+	fmt.Fprintf(h.out, "Streams: %d\n", len(streams))
+	fmt.Fprintf(h.out, "Segments Warmed: %d\n", total.Hits+total.Misses+total.Errors)
+	fmt.Fprintf(h.out, "Cache Hit: %d\n", total.Hits)
+	fmt.Fprintf(h.out, "Cache Miss: %d\n", total.Misses)
+	fmt.Fprintf(h.out, "Error Count: %d\n", total.Errors)
+	fmt.Fprintf(h.out, "Bytes Transferred: %s\n", formatBytes(total.Bytes))
+	fmt.Fprintf(h.out, "Cache Ratio: %.2f%%\n", total.HitRatio()*100)
+
+	if len(streams) > 0 {
+		fmt.Fprintf(h.out, "\n🎬 STREAMS:\n")
+		for i, stats := range streams {
+			fmt.Fprintf(h.out, "%d. %s: %d cycles, %d/%d hits (%.2f%%), %d errors, %s\n", i+1, urls[i], stats.Cycles,
+				stats.Hits, stats.Hits+stats.Misses+stats.Errors, stats.HitRatio()*100, stats.Errors, formatBytes(stats.Bytes))
+		}
+	}
+	fmt.Fprintf(h.out, "==========================================\n")
+}
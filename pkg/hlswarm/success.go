@@ -0,0 +1,47 @@
+package hlswarm
+
+import "fmt"
+
+// Success criteria deciding which warmed segments count as successful
+const (
+	// SuccessCacheHit counts cache hits, the default
+	SuccessCacheHit = "cache-hit"
+	// SuccessStatus2xx counts 2xx responses that were not flagged as suspicious,
+	// for origins without a usable cache header
+	SuccessStatus2xx = "status-2xx"
+	// SuccessBoth counts 2xx responses that were also cache hits
+	SuccessBoth = "both"
+)
+
+// ValidateSuccessCriteria checks a -success-criteria value
+func ValidateSuccessCriteria(criteria string) error {
+	switch criteria {
+	case "", SuccessCacheHit, SuccessStatus2xx, SuccessBoth:
+		return nil
+	}
+	return fmt.Errorf("must be %s, %s or %s", SuccessCacheHit, SuccessStatus2xx, SuccessBoth)
+}
+
+// succeeded reports whether a segment result counts as successful under the
+// given criteria. A 2xx response flagged as suspicious, e.g. for its content type
+// with -check-content-type, does not.
+func succeeded(r CacheStatus, criteria string) bool {
+	ok := r.Error == nil && r.StatusCode >= 200 && r.StatusCode < 300 && r.Suspicious == ""
+	switch criteria {
+	case SuccessStatus2xx:
+		return ok
+	case SuccessBoth:
+		return ok && r.Hit
+	default:
+		return r.Error == nil && r.Hit
+	}
+}
+
+// SuccessRatio returns the share (0-1) of segment requests that succeeded, or 0
+// when nothing was warmed
+func (r *WarmResult) SuccessRatio() float64 {
+	if r.TotalFiles == 0 {
+		return 0
+	}
+	return float64(r.Succeeded) / float64(r.TotalFiles)
+}
@@ -0,0 +1,65 @@
+package hlswarm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestTemplate replaces the plain GET of a stream's playlist or segment
+// requests with one carrying a body, for origins that authorize requests with
+// e.g. a signed POST. The body may contain the placeholders {url}, {path},
+// {query}, {timestamp} (Unix seconds) and {playback_id}, filled in per request.
+type RequestTemplate struct {
+	// Method defaults to POST
+	Method      string `yaml:"method" json:"method"`
+	Body        string `yaml:"body" json:"body"`
+	ContentType string `yaml:"content_type" json:"content_type"`
+}
+
+// validate checks that a template names a method that can carry a body
+func (t *RequestTemplate) validate() error {
+	if t == nil {
+		return nil
+	}
+	switch t.method() {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch:
+		return nil
+	}
+	return fmt.Errorf("invalid request method %q: must be GET, POST, PUT or PATCH", t.Method)
+}
+
+// method returns the template's method, POST when not set
+func (t *RequestTemplate) method() string {
+	if t.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(t.Method)
+}
+
+// body returns the template's body with its placeholders filled in for a request to rawURL
+func (t *RequestTemplate) body(rawURL, playbackID string) string {
+	var path, query string
+	if parsedURL, err := url.Parse(rawURL); err == nil {
+		path, query = parsedURL.Path, parsedURL.RawQuery
+	}
+	return strings.NewReplacer(
+		"{url}", rawURL,
+		"{path}", path,
+		"{query}", query,
+		"{timestamp}", strconv.FormatInt(time.Now().Unix(), 10),
+		"{playback_id}", playbackID,
+	).Replace(t.Body)
+}
+
+// requestTemplate returns the template for a stream's manifest or segment
+// requests, nil for plain requests
+func (s Stream) requestTemplate(manifest bool) *RequestTemplate {
+	if manifest {
+		return s.PlaylistRequest
+	}
+	return s.SegmentRequest
+}
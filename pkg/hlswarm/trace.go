@@ -0,0 +1,81 @@
+package hlswarm
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// tracer records the phase timings of one request through httptrace hooks.
+// Hooks may fire from the transport's goroutines, so access is locked.
+type tracer struct {
+	mu        sync.Mutex
+	start     time.Time
+	dnsStart  time.Time
+	connStart time.Time
+	tlsStart  time.Time
+	firstByte time.Time
+	timing    Timing
+}
+
+// withTracer returns a context whose requests report their phases to a new tracer
+func withTracer(ctx context.Context) (context.Context, *tracer) {
+	t := &tracer{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timing.Connected = true
+			t.timing.Reused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.timing.DNS = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			t.connStart = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			t.timing.Connect = time.Since(t.connStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.timing.TLS = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.timing.TTFB = t.firstByte.Sub(t.start)
+			t.mu.Unlock()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+// finish marks the end of the body and returns the recorded timings
+func (t *tracer) finish() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.firstByte.IsZero() {
+		t.timing.Transfer = time.Since(t.firstByte)
+	}
+	return t.timing
+}
@@ -0,0 +1,170 @@
+package hlswarm
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// generateUUID generates a random UUID v4
+func generateUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	// Set version (4) and variant bits
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%08X-%04X-%04X-%04X-%012X",
+		b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// extractBaseURL extracts the base URL (scheme + host) from a given URL
+func extractBaseURL(urlStr string) string {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+
+	// Return scheme + host (e.g., "https://example.com")
+	return parsedURL.Scheme + "://" + parsedURL.Host
+}
+
+// localPath returns the file path of a file:// URL
+func localPath(rawURL string) (string, bool) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Scheme != "file" {
+		return "", false
+	}
+	return filepath.FromSlash(parsedURL.Path), true
+}
+
+// cleanString removes non-printable characters that might corrupt terminal output
+func cleanString(s string) string {
+	// Remove null bytes and other problematic characters
+	s = strings.ReplaceAll(s, "\x00", "")
+
+	// Replace non-printable characters except for tab, newline, and carriage return
+	return strings.Map(func(r rune) rune {
+		// Allow normal printable ASCII characters and some basic whitespace
+		if (r >= 32 && r <= 126) || r == 9 || r == 10 || r == 13 {
+			return r
+		}
+		// Skip/remove problematic characters completely for URLs
+		return -1
+	}, s)
+}
+
+// truncate shortens s to at most n bytes, marking the cut with "..."
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// cleanURI removes control characters from a playlist URI line. Unlike
+// cleanString it keeps non-ASCII characters, which url.URL escapes on output,
+// so UTF-8 paths and opaque tokens survive intact
+func cleanURI(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// resolveURL resolves a relative URL against a base URL. Absolute paths keep
+// their query string and protocol-relative references ("//host/path") inherit
+// the base scheme. References with an empty host ("//", "///path") have nowhere
+// to go and are returned unresolved.
+func resolveURL(baseURL *url.URL, segment string) string {
+	// If segment is already a full URL, use it directly
+	if isHTTPURL(segment) {
+		return segment
+	}
+
+	// Protocol-relative references take the scheme of the playlist, falling back
+	// to https for playlists that were not fetched over HTTP (e.g. file://)
+	if rest, ok := strings.CutPrefix(segment, "//"); ok {
+		if end := strings.IndexAny(rest, "/?#"); rest == "" || end == 0 {
+			return segment
+		}
+		scheme := baseURL.Scheme
+		if scheme != "http" && scheme != "https" {
+			scheme = "https"
+		}
+		segment = scheme + ":" + segment
+	}
+
+	// Resolve relative URL
+	segmentURL, err := url.Parse(segment)
+	if err != nil {
+		// Stray "%" signs (e.g. in unencoded tokens) fail to parse; escape them
+		// and retry rather than returning an unresolved reference
+		segmentURL, err = url.Parse(escapeStrayPercents(segment))
+		if err != nil {
+			return segment
+		}
+	}
+
+	return baseURL.ResolveReference(segmentURL).String()
+}
+
+// escapeStrayPercents escapes each "%" that does not start a valid percent-encoding
+func escapeStrayPercents(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && (i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2])) {
+			b.WriteString("%25")
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// isHex reports whether c is a hexadecimal digit
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// isHTTPURL reports whether the URL uses the http or https scheme
+func isHTTPURL(urlStr string) bool {
+	scheme, _, ok := strings.Cut(urlStr, "://")
+	return ok && (strings.EqualFold(scheme, "http") || strings.EqualFold(scheme, "https"))
+}
+
+// sleepContext pauses for d, returning false if ctx is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "1.5 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
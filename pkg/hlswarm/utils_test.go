@@ -0,0 +1,65 @@
+package hlswarm
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestResolveURL(t *testing.T) {
+	tests := []struct {
+		base    string
+		segment string
+		want    string
+	}{
+		{"https://cdn.example.com/live/index.m3u8", "seg1.ts", "https://cdn.example.com/live/seg1.ts"},
+		{"https://cdn.example.com/live/hd/index.m3u8", "../sd/seg1.ts", "https://cdn.example.com/live/sd/seg1.ts"},
+		{"https://cdn.example.com/live/index.m3u8?token=abc", "seg1.ts", "https://cdn.example.com/live/seg1.ts"},
+		{"https://cdn.example.com/live/index.m3u8", "/other/seg1.ts", "https://cdn.example.com/other/seg1.ts"},
+		{"https://cdn.example.com/live/index.m3u8", "http://origin.example.com/seg1.ts", "http://origin.example.com/seg1.ts"},
+		{"https://cdn.example.com/live/index.m3u8", "//edge.example.com/seg1.ts", "https://edge.example.com/seg1.ts"},
+		{"https://cdn.example.com/live/index.m3u8?token=xyz", "/v1/seg10.ts?token=abc", "https://cdn.example.com/v1/seg10.ts?token=abc"},
+		{"https://cdn.example.com/live/index.m3u8", "//edge.example.com/v1/seg.ts?token=abc", "https://edge.example.com/v1/seg.ts?token=abc"},
+		{"https://cdn.example.com/live/index.m3u8", "seg1.ts?sig=a%2Bb%zz", "https://cdn.example.com/live/seg1.ts?sig=a%2Bb%zz"},
+		{"https://cdn.example.com/live/index.m3u8", "/v1/50%off/seg1.ts?token=a%2Fb", "https://cdn.example.com/v1/50%25off/seg1.ts?token=a%2Fb"},
+		{"https://cdn.example.com/live/index.m3u8", "vídeo/señal1.ts", "https://cdn.example.com/live/v%C3%ADdeo/se%C3%B1al1.ts"},
+		{"https://cdn.example.com/live/index.m3u8", "/日本/seg1.ts?q=é", "https://cdn.example.com/%E6%97%A5%E6%9C%AC/seg1.ts?q=é"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.segment, func(t *testing.T) {
+			base, err := url.Parse(tt.base)
+			if err != nil {
+				t.Fatalf("parsing base %q: %v", tt.base, err)
+			}
+			if got := resolveURL(base, tt.segment); got != tt.want {
+				t.Errorf("resolveURL(%q, %q) = %q, want %q", tt.base, tt.segment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveProtocolRelativeURL(t *testing.T) {
+	tests := []struct {
+		base    string
+		segment string
+		want    string
+	}{
+		{"http://origin.example.com/live/index.m3u8", "//cdn.example.com/seg1.ts", "http://cdn.example.com/seg1.ts"},
+		{"https://origin.example.com/live/index.m3u8", "//cdn.example.com/seg1.ts", "https://cdn.example.com/seg1.ts"},
+		{"file:///srv/live/index.m3u8", "//cdn.example.com/seg1.ts", "https://cdn.example.com/seg1.ts"},
+		{"https://origin.example.com/live/index.m3u8", "//cdn.example.com:8443/seg1.ts?token=abc", "https://cdn.example.com:8443/seg1.ts?token=abc"},
+		{"https://origin.example.com/live/index.m3u8", "//", "//"},
+		{"https://origin.example.com/live/index.m3u8", "///seg1.ts", "///seg1.ts"},
+		{"http://origin.example.com/live/index.m3u8", "//?token=abc", "//?token=abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base+" "+tt.segment, func(t *testing.T) {
+			base, err := url.Parse(tt.base)
+			if err != nil {
+				t.Fatalf("parsing base %q: %v", tt.base, err)
+			}
+			if got := resolveURL(base, tt.segment); got != tt.want {
+				t.Errorf("resolveURL(%q, %q) = %q, want %q", tt.base, tt.segment, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package hlswarm
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// -variant values; any other value is a bandwidth in bits per second
+const (
+	VariantAll     = "all"
+	VariantLowest  = "lowest"
+	VariantHighest = "highest"
+)
+
+// mediaTypes are the EXT-X-MEDIA TYPE values -media-types can select
+var mediaTypes = []string{"AUDIO", "VIDEO", "SUBTITLES", "CLOSED-CAPTIONS"}
+
+// streamVariant is an EXT-X-STREAM-INF or EXT-X-I-FRAME-STREAM-INF entry of a
+// master playlist
+type streamVariant struct {
+	URL       string
+	Bandwidth int64
+	IFrame    bool
+	// Media is the TYPE of an EXT-X-MEDIA rendition, empty for variants
+	Media string
+	// Groups holds the AUDIO, VIDEO, SUBTITLES and CLOSED-CAPTIONS group IDs the
+	// variant refers to
+	Groups []string
+}
+
+// rendition is an EXT-X-MEDIA entry of a master playlist that carries a URI
+type rendition struct {
+	URL   string
+	Type  string
+	Group string
+}
+
+// ParseVariantSelector validates a -variant value and returns the bandwidth it
+// names, or 0 for all, lowest and highest
+func ParseVariantSelector(value string) (int64, error) {
+	switch value {
+	case "", VariantAll, VariantLowest, VariantHighest:
+		return 0, nil
+	}
+	bandwidth, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || bandwidth <= 0 {
+		return 0, fmt.Errorf("must be %s, %s, %s or a bandwidth in bits per second", VariantAll, VariantLowest, VariantHighest)
+	}
+	return bandwidth, nil
+}
+
+// ParseMediaTypes validates -media-types values and returns them upper-cased, or
+// nil for all types when there are none
+func ParseMediaTypes(values []string) ([]string, error) {
+	var types []string
+	for _, kind := range values {
+		kind = strings.ToUpper(kind)
+		if !slices.Contains(mediaTypes, kind) {
+			return nil, fmt.Errorf("unknown media type %q, must be one of %s", kind, strings.ToLower(strings.Join(mediaTypes, ", ")))
+		}
+		types = append(types, kind)
+	}
+	return types, nil
+}
+
+// selectVariants picks the variants of a master playlist to descend into. A
+// bandwidth selects the highest variant not above it, or the lowest variant when
+// all of them are. Variants without a BANDWIDTH attribute sort as 0.
+func selectVariants(variants []streamVariant, selector string) []streamVariant {
+	if selector == "" || selector == VariantAll || len(variants) <= 1 {
+		return variants
+	}
+
+	sorted := slices.Clone(variants)
+	slices.SortStableFunc(sorted, func(a, b streamVariant) int {
+		return cmp.Compare(a.Bandwidth, b.Bandwidth)
+	})
+
+	switch selector {
+	case VariantLowest:
+		return sorted[:1]
+	case VariantHighest:
+		return sorted[len(sorted)-1:]
+	}
+
+	limit, _ := ParseVariantSelector(selector)
+	chosen := sorted[0]
+	for _, variant := range sorted {
+		if variant.Bandwidth <= limit {
+			chosen = variant
+		}
+	}
+	return []streamVariant{chosen}
+}
+
+// selectRenditions keeps the renditions in the groups the selected variants
+// refer to, so that e.g. only the audio played with the chosen variant is warmed
+func selectRenditions(renditions []rendition, variants []streamVariant) []rendition {
+	groups := make(map[string]bool)
+	for _, variant := range variants {
+		for _, group := range variant.Groups {
+			groups[group] = true
+		}
+	}
+
+	var selected []rendition
+	for _, r := range renditions {
+		if groups[r.Group] {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// variantGroups returns the rendition group IDs an EXT-X-STREAM-INF tag refers to,
+// prefixed with the media type since group IDs are only unique per type
+func variantGroups(attrs map[string]string) []string {
+	var groups []string
+	for _, kind := range mediaTypes {
+		if id := attrs[kind]; id != "" && id != "NONE" {
+			groups = append(groups, kind+"/"+id)
+		}
+	}
+	return groups
+}
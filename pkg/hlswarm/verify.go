@@ -0,0 +1,86 @@
+package hlswarm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Verification compares the cache status of each warmed segment with a
+// re-request made a delay later, showing whether warming stuck
+type Verification struct {
+	Delay time.Duration
+	// Hits counts segments that were cache hits on re-request, Evicted those that
+	// were hits when warmed but missed later, and NeverCached those that missed both
+	// times; Errors counts re-requests that failed
+	Hits        int
+	Evicted     int
+	NeverCached int
+	Errors      int
+	Details     []VerifiedSegment
+}
+
+// VerifiedSegment pairs the warming request for a segment with its re-request
+type VerifiedSegment struct {
+	Before CacheStatus
+	After  CacheStatus
+}
+
+// verifyWarmed waits for the verify delay, then re-requests every segment that was
+// warmed successfully and compares its cache status with the warming request.
+// Re-requests are not retried until they hit, since that would hide evictions.
+func (h *HLSWarmer) verifyWarmed(ctx context.Context, stream Stream, results []CacheStatus) *Verification {
+	verification := &Verification{Delay: h.verifyDelay}
+
+	var warmed []CacheStatus
+	for _, r := range results {
+		if r.Error == nil && r.StatusCode < 400 {
+			warmed = append(warmed, r)
+		}
+	}
+	if len(warmed) == 0 {
+		return verification
+	}
+
+	h.log.Info("Waiting to verify warmed segments", Icon("⏳"), "stream", stream.URL, "delay", h.verifyDelay, "segments", len(warmed))
+	if !sleepContext(ctx, h.verifyDelay) {
+		return verification
+	}
+
+	verification.Details = make([]VerifiedSegment, len(warmed))
+	sem := make(chan struct{}, h.maxWorkers)
+	var wg sync.WaitGroup
+	for i, before := range warmed {
+		segment := Segment{
+			URL:           before.URL,
+			ByteRange:     before.ByteRange,
+			IsKey:         before.IsKey,
+			IsInit:        before.IsInit,
+			Discontinuity: before.Discontinuity,
+		}
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			after := h.fetchWithRetries(withMirror(withEdge(ctx, before.Edge), before.Mirror), stream, segment)
+			verification.Details[i] = VerifiedSegment{Before: before, After: after}
+		})
+	}
+	wg.Wait()
+
+	for _, v := range verification.Details {
+		switch {
+		case v.After.Error != nil || v.After.StatusCode >= 400:
+			verification.Errors++
+		case v.After.Hit:
+			verification.Hits++
+		case v.Before.Hit:
+			verification.Evicted++
+		default:
+			verification.NeverCached++
+		}
+	}
+
+	h.log.Info("Verified warmed segments", Icon("🔁"), "stream", stream.URL, "hits", verification.Hits,
+		"evicted", verification.Evicted, "never_cached", verification.NeverCached, "errors", verification.Errors)
+	return verification
+}
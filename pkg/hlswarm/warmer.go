@@ -0,0 +1,1632 @@
+// Package hlswarm warms HLS and DASH streams through CDN caches by requesting their
+// segments ahead of viewers. Create a warmer with New, then warm a playlist once
+// with WarmM3U8, a list of segment URLs with WarmSegments, or keep streams warm
+// with RunDaemon.
+package hlswarm
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/time/rate"
+)
+
+// HLSWarmer handles warming of HLS streams
+type HLSWarmer struct {
+	client      *http.Client
+	edgeClients map[string]*http.Client
+	edgeIPs     []string
+	maxWorkers  int
+	// perHostWorkers caps in-flight segment requests per host across all streams,
+	// using one semaphore per host in hostSlots
+	perHostWorkers     int
+	adaptiveWorkers    bool
+	minWorkers         int
+	workerCeiling      int
+	hostSlots          map[string]chan struct{}
+	hostSlotsMu        sync.Mutex
+	manifests          map[string]*cachedManifest
+	manifestsMu        sync.Mutex
+	userAgents         []string
+	minimalHeaders     bool
+	userAgentIndex     atomic.Uint64
+	headers            map[string]string
+	basicAuth          *url.Userinfo
+	tracerProvider     trace.TracerProvider
+	spans              trace.Tracer
+	baseURL            string
+	segmentKeywords    []string
+	noSegmentFilter    bool
+	redactHeaders      map[string]bool
+	cookies            []*http.Cookie
+	method             string
+	cacheHeader        string
+	cacheHitValue      string
+	ageFallback        bool
+	referer            string
+	origin             string
+	playbackID         string
+	cacheStats         map[string]CacheStatus
+	mu                 sync.RWMutex
+	interval           time.Duration
+	autoInterval       bool
+	liveEdge           bool
+	minInterval        time.Duration
+	maxInterval        time.Duration
+	daemonMode         bool
+	log                *slog.Logger
+	quiet              bool
+	out                io.Writer
+	sinks              []ResultSink
+	onSegment          func(CacheStatus)
+	onCycle            func(*WarmResult)
+	outputMu           sync.Mutex
+	processedURLs      map[string]time.Time
+	processedTTL       time.Duration
+	stateFile          string
+	maxTracked         int
+	dedupIgnoreQuery   []string
+	rewarmLast         int
+	maxRetries         int
+	retryBaseDelay     time.Duration
+	maxRetryAfter      time.Duration
+	breakerFailures    int
+	breakerErrorRate   float64
+	breakerMaxBackoff  time.Duration
+	webhookURL         string
+	webhookMinHitRatio float64
+	webhookInterval    time.Duration
+	webhookClient      *http.Client
+	webhookWG          sync.WaitGroup
+	staleCycles        int
+	checkContinuity    bool
+	strict             bool
+	warned             map[string]bool
+	warnedMu           sync.Mutex
+	warmUntilHit       int
+	warmUntilHitDelay  time.Duration
+	maxBodyBytes       int64
+	checkContentType   bool
+	successCriteria    string
+	compareSamples     int
+	purge              bool
+	purgeMethod        string
+	purgeHeaders       map[string]string
+	predictAhead       int
+	jitter             float64
+	predictions        map[string]*predictionStats
+	warmFrom           time.Duration
+	warmTo             time.Duration
+	minSegmentBytes    int64
+	edgeFirst          int
+	segmentsLimit      int
+	prefetchBytes      int64
+	acceptEncoding     string
+	hosts              hostFilter
+	verify             bool
+	verifyDelay        time.Duration
+	maxDuration        time.Duration
+	variant            string
+	mediaTypes         []string
+	requestTimeout     time.Duration
+	limiter            *rate.Limiter
+	pace               bool
+	metrics            *metrics
+	metricsAddr        string
+	streams            map[string]Stream
+	streamMu           sync.Mutex
+	streamActive       map[string]bool
+	streamPaused       map[string]time.Time
+	streamEnded        map[string]bool
+	streamTarget       map[string]time.Duration
+	streamScales       map[string]*workerScale
+	streamBreakers     map[string]*breaker
+	streamIdle         map[string]int
+	streamBackoffs     map[string]*intervalBackoff
+	streamSequences    map[string]map[string]sequenceMark
+	streamEdges        map[string]*liveEdge
+	streamWebhooks     map[string]*webhookState
+	streamMirrors      map[string]*mirrorPicker
+	mirrors            []Mirror
+	streamRuns         map[string]*streamRun
+	streamResults      map[string]*WarmResult
+	streamStats        map[string]*StreamStats
+	streamWG           sync.WaitGroup
+	cycleWG            sync.WaitGroup
+	apiAddr            string
+	keepAlive          bool
+}
+
+// New creates a new HLSWarmer instance
+func New(config Config) *HLSWarmer {
+	// Set defaults
+	if config.SuccessCriteria == "" {
+		config.SuccessCriteria = SuccessCacheHit
+	}
+	if config.Workers == 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.MinWorkers == 0 {
+		config.MinWorkers = 1
+	}
+	if config.MaxWorkers == 0 {
+		config.MaxWorkers = config.Workers * DefaultMaxWorkersFactor
+	}
+	autoInterval := config.Interval == 0
+	if autoInterval {
+		config.Interval = DefaultInterval
+	}
+	if config.TTL == 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.RetryBaseDelay == 0 {
+		config.RetryBaseDelay = DefaultRetryBaseDelay
+	}
+	if config.WarmUntilHitDelay == 0 {
+		config.WarmUntilHitDelay = DefaultWarmUntilHitDelay
+	}
+	if config.MaxRetryAfter == 0 {
+		config.MaxRetryAfter = DefaultMaxRetryAfter
+	}
+	if config.BreakerErrorRate == 0 {
+		config.BreakerErrorRate = DefaultBreakerErrorRate
+	}
+	if config.BreakerMaxBackoff == 0 {
+		config.BreakerMaxBackoff = DefaultBreakerMaxBackoff
+	}
+	if config.WebhookInterval == 0 {
+		config.WebhookInterval = DefaultWebhookInterval
+	}
+	if config.VerifyDelay == 0 {
+		config.VerifyDelay = DefaultVerifyDelay
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = DefaultMaxRedirects
+	}
+	if config.ConnectTimeout == 0 {
+		config.ConnectTimeout = DefaultConnectTimeout
+	}
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = DefaultRequestTimeout
+	}
+	if config.PurgeMethod == "" {
+		config.PurgeMethod = DefaultPurgeMethod
+	}
+	if config.PlaybackID == "" && !config.MinimalHeaders {
+		config.PlaybackID = generateUUID()
+	}
+	if len(config.Outputs) == 0 {
+		config.Outputs = []string{OutputText}
+	}
+	if len(config.UserAgents) == 0 {
+		// An empty User-Agent keeps the transport from adding its own
+		config.UserAgents = []string{defaultUserAgent}
+		if config.MinimalHeaders {
+			config.UserAgents = []string{""}
+		}
+	}
+	if config.SegmentKeywords == nil {
+		config.SegmentKeywords = DefaultSegmentKeywords
+	}
+	if config.TracerProvider == nil {
+		config.TracerProvider = noop.NewTracerProvider()
+	}
+	if config.CacheHitValue == "" {
+		config.CacheHitValue = DefaultCacheHitValue
+	}
+	if config.Method == "" {
+		config.Method = http.MethodGet
+	}
+	if config.Debug {
+		config.LogLevel = min(config.LogLevel, slog.LevelDebug)
+	}
+
+	var m *metrics
+	if config.MetricsAddr != "" {
+		m = newMetrics()
+	}
+
+	// Keep stdout clean for machine-readable results
+	outputs := config.Outputs
+	var out io.Writer = os.Stdout
+	if slices.Contains(outputs, OutputJSON) || (slices.Contains(outputs, OutputCSV) && config.CSVReport.toStdout()) {
+		out = os.Stderr
+	}
+
+	streams := make(map[string]Stream, len(config.Streams))
+	for _, stream := range config.Streams {
+		streams[stream.URL] = stream
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != nil {
+		proxy = http.ProxyURL(config.Proxy)
+	}
+
+	jar := config.CookieJar
+	if jar == nil {
+		jar, _ = cookiejar.New(nil)
+	}
+
+	// A single limiter shared by all workers and streams enforces the global ceiling
+	var limiter *rate.Limiter
+	if config.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(config.Rate), 1)
+	}
+
+	// Each edge gets its own client so pooled connections never cross edges. In
+	// -ip-version both mode the two address families are warmed like two edges.
+	edges := config.EdgeIPs
+	edgeClients := make(map[string]*http.Client, len(config.EdgeIPs))
+	for _, edgeIP := range config.EdgeIPs {
+		edgeClients[edgeIP] = newHTTPClient(config, jar, proxy, edgeIP, "")
+	}
+	network := ""
+	switch config.IPVersion {
+	case IPVersion4:
+		network = "tcp4"
+	case IPVersion6:
+		network = "tcp6"
+	case IPVersionBoth:
+		edges = []string{edgeIPv4, edgeIPv6}
+		edgeClients[edgeIPv4] = newHTTPClient(config, jar, proxy, "", "tcp4")
+		edgeClients[edgeIPv6] = newHTTPClient(config, jar, proxy, "", "tcp6")
+	}
+
+	// Webhooks share the proxy, TLS settings and Transport of segment requests but
+	// not their edges, cookies or host allowlist, which are about the streams
+	webhookClient := newHTTPClient(config, nil, proxy, "", "")
+	webhookClient.CheckRedirect = nil
+
+	h := &HLSWarmer{
+		client:             newHTTPClient(config, jar, proxy, "", network),
+		edgeClients:        edgeClients,
+		edgeIPs:            edges,
+		maxWorkers:         config.Workers,
+		perHostWorkers:     config.PerHostWorkers,
+		adaptiveWorkers:    config.AdaptiveWorkers,
+		minWorkers:         config.MinWorkers,
+		workerCeiling:      config.MaxWorkers,
+		hostSlots:          make(map[string]chan struct{}),
+		manifests:          make(map[string]*cachedManifest),
+		userAgents:         config.UserAgents,
+		minimalHeaders:     config.MinimalHeaders,
+		headers:            config.Headers,
+		basicAuth:          config.BasicAuth,
+		tracerProvider:     config.TracerProvider,
+		onSegment:          config.OnSegment,
+		onCycle:            config.OnCycle,
+		spans:              config.TracerProvider.Tracer(tracingName),
+		baseURL:            config.BaseURL,
+		maxDuration:        config.MaxDuration,
+		segmentKeywords:    config.SegmentKeywords,
+		noSegmentFilter:    config.NoSegmentFilter,
+		redactHeaders:      redactSet(config.RedactHeaders),
+		cookies:            config.Cookies,
+		method:             config.Method,
+		cacheHeader:        config.CacheHeader,
+		cacheHitValue:      config.CacheHitValue,
+		ageFallback:        !config.DisableAgeFallback,
+		referer:            config.Referer,
+		origin:             config.Origin,
+		playbackID:         config.PlaybackID,
+		cacheStats:         make(map[string]CacheStatus),
+		interval:           config.Interval,
+		autoInterval:       autoInterval,
+		liveEdge:           config.LiveEdge,
+		minInterval:        config.MinInterval,
+		maxInterval:        config.MaxInterval,
+		daemonMode:         config.DaemonMode,
+		log:                newLogger(out, config.LogFormat, config.LogLevel),
+		quiet:              config.Quiet,
+		out:                out,
+		processedURLs:      make(map[string]time.Time),
+		processedTTL:       config.TTL,
+		stateFile:          config.StateFile,
+		maxTracked:         config.MaxTrackedSegments,
+		dedupIgnoreQuery:   config.DedupIgnoreQuery,
+		rewarmLast:         config.RewarmLast,
+		maxRetries:         config.MaxRetries,
+		retryBaseDelay:     config.RetryBaseDelay,
+		maxRetryAfter:      config.MaxRetryAfter,
+		breakerFailures:    config.BreakerFailures,
+		breakerErrorRate:   config.BreakerErrorRate,
+		breakerMaxBackoff:  config.BreakerMaxBackoff,
+		webhookURL:         config.WebhookURL,
+		webhookMinHitRatio: config.WebhookMinHitRatio,
+		webhookInterval:    config.WebhookInterval,
+		webhookClient:      webhookClient,
+		staleCycles:        config.StaleCycles,
+		checkContinuity:    config.CheckContinuity,
+		strict:             config.Strict,
+		warned:             make(map[string]bool),
+		warmUntilHit:       config.WarmUntilHit,
+		warmUntilHitDelay:  config.WarmUntilHitDelay,
+		maxBodyBytes:       config.MaxBodyBytes,
+		checkContentType:   config.CheckContentType,
+		successCriteria:    config.SuccessCriteria,
+		compareSamples:     config.CompareMethods,
+		purge:              config.Purge,
+		purgeMethod:        config.PurgeMethod,
+		purgeHeaders:       config.PurgeHeaders,
+		predictAhead:       config.PredictAhead,
+		jitter:             config.Jitter,
+		predictions:        make(map[string]*predictionStats),
+		warmFrom:           config.WarmFrom,
+		warmTo:             config.WarmTo,
+		minSegmentBytes:    config.MinSegmentBytes,
+		edgeFirst:          config.EdgeFirst,
+		segmentsLimit:      config.SegmentsLimit,
+		prefetchBytes:      config.PrefetchBytes,
+		acceptEncoding:     config.AcceptEncoding,
+		hosts:              hostFilter{allow: config.AllowHosts, deny: config.DenyHosts},
+		verify:             config.Verify,
+		verifyDelay:        config.VerifyDelay,
+		variant:            config.Variant,
+		mediaTypes:         config.MediaTypes,
+		requestTimeout:     config.RequestTimeout,
+		limiter:            limiter,
+		pace:               config.Pace,
+		metrics:            m,
+		metricsAddr:        config.MetricsAddr,
+		streams:            streams,
+		streamActive:       make(map[string]bool),
+		streamPaused:       make(map[string]time.Time),
+		streamEnded:        make(map[string]bool),
+		streamTarget:       make(map[string]time.Duration),
+		streamScales:       make(map[string]*workerScale),
+		streamBreakers:     make(map[string]*breaker),
+		streamIdle:         make(map[string]int),
+		streamBackoffs:     make(map[string]*intervalBackoff),
+		streamSequences:    make(map[string]map[string]sequenceMark),
+		streamEdges:        make(map[string]*liveEdge),
+		streamWebhooks:     make(map[string]*webhookState),
+		streamMirrors:      make(map[string]*mirrorPicker),
+		mirrors:            config.Mirrors,
+		streamRuns:         make(map[string]*streamRun),
+		streamResults:      make(map[string]*WarmResult),
+		streamStats:        make(map[string]*StreamStats),
+		apiAddr:            config.APIAddr,
+		keepAlive:          config.KeepAlive || config.APIAddr != "",
+	}
+	h.sinks = newSinks(h, outputs, config)
+	return h
+}
+
+// newHTTPClient creates the HTTP client used for requests. A non-empty edgeIP
+// sends every connection to that address instead of the one DNS resolves, while
+// the Host header and TLS server name still come from the request URL. A non-empty
+// network ("tcp4" or "tcp6") restricts connections to that address family.
+func newHTTPClient(config Config, jar http.CookieJar, proxy func(*http.Request) (*url.URL, error), edgeIP, network string) *http.Client {
+	redirectPolicy := checkRedirect(config.MaxRedirects, hostFilter{allow: config.AllowHosts, deny: config.DenyHosts})
+	if config.Transport != nil {
+		return &http.Client{Jar: jar, Transport: config.Transport, CheckRedirect: redirectPolicy}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: defaultKeepAlive,
+	}
+
+	dial := func(ctx context.Context, defaultNetwork, addr string) (net.Conn, error) {
+		if network != "" {
+			defaultNetwork = network
+		}
+		if edgeIP != "" {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(edgeIP, port)
+		}
+		return dialer.DialContext(ctx, defaultNetwork, addr)
+	}
+
+	// A custom dialer disables HTTP/2 unless it is forced; -http1-only turns it
+	// off entirely by leaving no ALPN upgrade for "h2"
+	transport := &http.Transport{
+		Proxy:               proxy,
+		DialContext:         dial,
+		ForceAttemptHTTP2:   !config.HTTP1Only,
+		TLSClientConfig:     config.TLSConfig.Clone(),
+		TLSHandshakeTimeout: config.ConnectTimeout,
+		MaxIdleConns:        defaultMaxIdleConns,
+		MaxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     defaultIdleConnTimeout,
+	}
+	if config.HTTP1Only {
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	return &http.Client{
+		Jar:           jar,
+		Transport:     transport,
+		CheckRedirect: redirectPolicy,
+	}
+}
+
+// edgeKey is the context key carrying the edge IP a segment request is sent to
+type edgeKey struct{}
+
+// withEdge returns a context whose segment requests go to the given edge IP
+func withEdge(ctx context.Context, edgeIP string) context.Context {
+	if edgeIP == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, edgeKey{}, edgeIP)
+}
+
+// edgeFromContext returns the edge IP set by withEdge, or "" for the default route
+func edgeFromContext(ctx context.Context) string {
+	edgeIP, _ := ctx.Value(edgeKey{}).(string)
+	return edgeIP
+}
+
+// clientFor returns the client for the edge selected in ctx
+func (h *HLSWarmer) clientFor(ctx context.Context) *http.Client {
+	if client, ok := h.edgeClients[edgeFromContext(ctx)]; ok {
+		return client
+	}
+	return h.client
+}
+
+// Logger returns the logger the warmer writes progress to
+func (h *HLSWarmer) Logger() *slog.Logger {
+	return h.log
+}
+
+// RecordResult passes a one-shot warm result to every result sink, printing or
+// writing it in the configured output formats
+func (h *HLSWarmer) RecordResult(result *WarmResult) {
+	for _, sink := range h.sinks {
+		sink.Record(result)
+	}
+}
+
+// GetPlaybackSessionID returns the current playback session ID
+func (h *HLSWarmer) GetPlaybackSessionID() string {
+	return h.playbackID
+}
+
+// streamFor returns the effective settings for a stream, filling anything not
+// configured for that stream from the warmer-wide configuration
+func (h *HLSWarmer) streamFor(m3u8URL string) Stream {
+	h.streamMu.Lock()
+	stream, ok := h.streams[m3u8URL]
+	h.streamMu.Unlock()
+	if !ok {
+		stream = Stream{URL: m3u8URL}
+	}
+
+	if stream.Referer == "" {
+		stream.Referer = h.referer
+	}
+	if stream.Origin == "" {
+		stream.Origin = h.origin
+	}
+	if stream.Interval == 0 {
+		stream.Interval = h.interval
+		stream.AutoInterval = h.autoInterval
+	}
+	if stream.TTL == 0 {
+		stream.TTL = h.processedTTL
+	}
+	if stream.RewarmLast == 0 {
+		stream.RewarmLast = h.rewarmLast
+	}
+	if len(stream.Mirrors) == 0 {
+		stream.Mirrors = h.mirrors
+	}
+
+	return stream
+}
+
+// WarmM3U8 warms an M3U8 playlist and its segments, stopping early when ctx is cancelled
+func (h *HLSWarmer) WarmM3U8(ctx context.Context, m3u8URL string) (*WarmResult, error) {
+	startTime := time.Now()
+	stream := h.detectHeaders(h.streamFor(m3u8URL), h.resolveBase(m3u8URL))
+
+	if h.maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.maxDuration)
+		defer cancel()
+	}
+
+	h.log.Info("Starting to warm M3U8", Icon("🔥"), "stream", m3u8URL)
+	ctx, span := h.spans.Start(ctx, "warm playlist", trace.WithAttributes(attribute.String("hls.stream", m3u8URL)))
+
+	// Download and parse M3U8 file
+	playlist, err := h.parseManifest(ctx, stream)
+	if err != nil {
+		err = fmt.Errorf("M3U8 parse error: %v", err)
+		endCycleSpan(span, nil, err)
+		return nil, err
+	}
+	return h.warmPlaylist(ctx, stream, playlist, startTime, span), nil
+}
+
+// WarmSegments warms a caller-supplied list of segment URLs, for callers that
+// parse their manifests themselves. No playlist is fetched; the URLs go through
+// the same deduplication, limits, worker pool and cache detection as a playlist's
+// segments. The result is reported under the first URL, whose configured stream
+// settings, if any, apply, and Referer and Origin are auto-detected from it.
+func (h *HLSWarmer) WarmSegments(ctx context.Context, urls []string) (*WarmResult, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no segment URLs to warm")
+	}
+	startTime := time.Now()
+
+	playlist := &Playlist{}
+	for _, segmentURL := range urls {
+		if !isHTTPURL(segmentURL) {
+			return nil, fmt.Errorf("invalid segment URL %q: must be http or https", segmentURL)
+		}
+		if !h.hosts.allowed(segmentURL) {
+			playlist.Disallowed++
+			continue
+		}
+		playlist.Segments = append(playlist.Segments, Segment{URL: segmentURL})
+	}
+	if len(playlist.Segments) == 0 {
+		return nil, fmt.Errorf("no segment URLs to warm: all %d are on disallowed hosts", len(urls))
+	}
+	stream := h.detectHeaders(h.streamFor(urls[0]), urls[0])
+
+	if h.maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.maxDuration)
+		defer cancel()
+	}
+
+	h.log.Info("Starting to warm segment list", Icon("🔥"), "stream", stream.URL, "segments", len(urls))
+	ctx, span := h.spans.Start(ctx, "warm segments", trace.WithAttributes(attribute.String("hls.stream", stream.URL)))
+	return h.warmPlaylist(ctx, stream, playlist, startTime, span), nil
+}
+
+// detectHeaders fills a stream's Referer and Origin from originURL when they are
+// not set. Only the local copy is filled in so concurrent streams on other hosts
+// are unaffected. Local playlists take them from -base-url, if anything, and
+// -minimal-headers leaves them unset.
+func (h *HLSWarmer) detectHeaders(stream Stream, originURL string) Stream {
+	if _, local := localPath(originURL); local || h.minimalHeaders {
+		originURL = ""
+	}
+	if stream.Referer == "" && originURL != "" {
+		if baseReferer := extractBaseURL(originURL); baseReferer != "" {
+			stream.Referer = baseReferer
+			h.log.Info("Auto-detected Referer", Icon("🔗"), "stream", stream.URL, "referer", baseReferer)
+		}
+	}
+	if stream.Origin == "" && originURL != "" {
+		if baseOrigin := extractBaseURL(originURL); baseOrigin != "" {
+			stream.Origin = baseOrigin
+			h.log.Info("Auto-detected Origin", Icon("🌐"), "stream", stream.URL, "origin", baseOrigin)
+		}
+	}
+	return stream
+}
+
+// warmPlaylist warms the segments of a parsed playlist and collects the result,
+// ending span with it
+func (h *HLSWarmer) warmPlaylist(ctx context.Context, stream Stream, playlist *Playlist, startTime time.Time, span trace.Span) *WarmResult {
+	m3u8URL := stream.URL
+	segments := playlist.Segments
+
+	if len(playlist.Variants) > 0 {
+		h.log.Info("Found segments", Icon("📋"), "stream", m3u8URL, "segments", playlist.mediaCount(), "variants", len(playlist.Variants))
+	} else {
+		h.log.Info("Found segments", Icon("📋"), "stream", m3u8URL, "segments", playlist.mediaCount())
+	}
+
+	if playlist.Disallowed > 0 {
+		h.log.Warn("Skipped segments and playlists on disallowed hosts", Icon("🚫"), "stream", m3u8URL, "count", playlist.Disallowed)
+	}
+
+	// Variants and renditions often share init segments, keys or even media
+	// segments; warm each distinct resource once
+	segments, duplicates := dedupSegments(segments)
+	if duplicates > 0 {
+		h.log.Info("Skipped duplicate segments", Icon("♻️"), "stream", m3u8URL, "count", duplicates)
+	}
+
+	if h.warmFrom > 0 || h.warmTo > 0 {
+		segments = timeWindow(segments, h.warmFrom, h.warmTo)
+		h.log.Info("Warming time window", Icon("⏱️"), "stream", m3u8URL, "from", h.warmFrom, "to", h.warmTo,
+			"segments", countMedia(segments))
+	}
+
+	var deferred []Segment
+	if h.segmentsLimit > 0 {
+		segments, deferred = limitSegments(segments, h.segmentsLimit)
+		if len(deferred) > 0 {
+			h.log.Info("Segment limit reached, skipping older segments", Icon("⏭️"), "stream", m3u8URL,
+				"limit", h.segmentsLimit, "deferred", len(deferred))
+		}
+	}
+
+	keyCount, initCount := 0, 0
+	for _, segment := range segments {
+		if segment.IsKey {
+			keyCount++
+		} else if segment.IsInit {
+			initCount++
+		}
+	}
+	if initCount > 0 {
+		h.log.Info("Found init segments", Icon("🧩"), "stream", m3u8URL, "count", initCount)
+	}
+	if keyCount > 0 {
+		h.log.Info("Found encryption keys", Icon("🔑"), "stream", m3u8URL, "count", keyCount)
+	}
+
+	// Invalidate cached copies first so that warming fetches fresh content
+	var purge *Purge
+	if h.purge {
+		purge = h.purgeSegments(ctx, stream, segments)
+	}
+
+	// Compare request methods while the sampled segments are still cold
+	var comparisons []MethodComparison
+	if h.compareSamples > 0 {
+		comparisons = h.compareMethods(ctx, stream, segments)
+	}
+
+	// Warm segments in parallel
+	results := h.warmPlaylistSegments(ctx, stream, segments)
+
+	// Collect results
+	result := newWarmResult(m3u8URL, results, time.Since(startTime), h.successCriteria)
+	result.Variants = playlist.Variants
+	result.Duplicates = duplicates
+	result.Disallowed = playlist.Disallowed
+	result.Deferred = len(deferred)
+	result.Purge = purge
+	result.MethodComparison = comparisons
+	result.Continuity = h.continuityOf(m3u8URL, nil, results)
+	result.ParseWarnings = playlist.Warnings
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.DeadlineExceeded = true
+		result.Unwarmed = len(segments)*max(len(h.edgeIPs), 1) - len(results)
+		h.log.Warn("Max duration reached, stopped warming", Icon("⏰"), "stream", m3u8URL,
+			"max_duration", h.maxDuration, "unwarmed", result.Unwarmed)
+	}
+
+	if h.verify && !result.DeadlineExceeded {
+		result.Verification = h.verifyWarmed(ctx, stream, results)
+	}
+
+	endCycleSpan(span, result, nil)
+	return result
+}
+
+// dedupSegments drops repeated segments, keeping the first occurrence of each URL
+// and byte range, and returns the number dropped
+func dedupSegments(segments []Segment) ([]Segment, int) {
+	seen := make(map[string]bool, len(segments))
+	unique := make([]Segment, 0, len(segments))
+	for _, segment := range segments {
+		if seen[segment.key()] {
+			continue
+		}
+		seen[segment.key()] = true
+		unique = append(unique, segment)
+	}
+	return unique, len(segments) - len(unique)
+}
+
+// newWarmResult aggregates segment results into a WarmResult
+func newWarmResult(m3u8URL string, results []CacheStatus, duration time.Duration, criteria string) *WarmResult {
+	result := &WarmResult{
+		M3U8URL:         m3u8URL,
+		TotalFiles:      len(results),
+		Duration:        duration,
+		Details:         results,
+		StatusCodes:     make(map[int]int),
+		SuccessCriteria: criteria,
+	}
+
+	// Calculate statistics
+	latencies := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		latencies = append(latencies, r.Duration)
+		if r.Error != nil {
+			result.Errors = append(result.Errors, r.Error)
+		} else if r.Hit {
+			result.CachedFiles++
+		}
+		if succeeded(r, criteria) {
+			result.Succeeded++
+		}
+		if r.Suspicious != "" {
+			result.SuspiciousFiles++
+		}
+		if r.StatusCode > 0 {
+			result.StatusCodes[r.StatusCode]++
+		}
+		result.TotalBytes += max(r.Size, 0)
+		result.WireBytes += max(r.WireBytes, 0)
+		if r.Error == nil && r.StatusCode < 400 {
+			result.TotalDuration += r.MediaDuration
+		}
+		switch {
+		case r.Timing.Reused:
+			result.ReusedConns++
+		case r.Timing.Connected:
+			result.NewConns++
+		}
+	}
+
+	slices.Sort(latencies)
+	result.P50, result.P95, result.P99 = percentile(latencies, 50), percentile(latencies, 95), percentile(latencies, 99)
+
+	return result
+}
+
+// connReuseRatio returns the share (0-1) of segment requests that reused a
+// keep-alive connection, or 0 when none got a connection
+func (r *WarmResult) connReuseRatio() float64 {
+	if r.ReusedConns+r.NewConns == 0 {
+		return 0
+	}
+	return float64(r.ReusedConns) / float64(r.ReusedConns+r.NewConns)
+}
+
+// realTimeRatio returns how many times faster than real time content was warmed,
+// or 0 when no durations are known
+func (r *WarmResult) realTimeRatio() float64 {
+	if r.TotalDuration <= 0 || r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.TotalDuration) / float64(r.Duration)
+}
+
+// warmPlaylistSegments warms init segments and keys first, then the media segments
+func (h *HLSWarmer) warmPlaylistSegments(ctx context.Context, stream Stream, segments []Segment) []CacheStatus {
+	var priority, media []Segment
+	for _, segment := range segments {
+		if segment.IsInit || segment.IsKey {
+			priority = append(priority, segment)
+		} else {
+			media = append(media, segment)
+		}
+	}
+
+	if h.edgeFirst > 0 {
+		media = edgeFirst(media, h.edgeFirst)
+	}
+
+	return append(h.warmSegments(ctx, stream, priority), h.warmSegments(ctx, stream, media)...)
+}
+
+// timeWindow keeps the media segments that overlap the [from, to) window of
+// playback time, measured from the start of each media playlist; a zero to means
+// no upper bound. Keys and init segments are always kept since playback needs them.
+func timeWindow(segments []Segment, from, to time.Duration) []Segment {
+	var kept []Segment
+	for _, segment := range segments {
+		if !segment.IsKey && !segment.IsInit {
+			end := segment.Start + segment.Duration
+			if end <= from || (to > 0 && segment.Start >= to) {
+				continue
+			}
+		}
+		kept = append(kept, segment)
+	}
+	return kept
+}
+
+// limitSegments keeps the newest n media segments, taken from the end of each
+// playlist in turn like edgeFirst, and returns them with the media segments
+// deferred past the limit. Keys and init segments are always kept, and kept
+// segments stay in playlist order.
+func limitSegments(segments []Segment, n int) (kept, deferred []Segment) {
+	var media []Segment
+	for _, segment := range segments {
+		if !segment.IsKey && !segment.IsInit {
+			media = append(media, segment)
+		}
+	}
+	if len(media) <= n {
+		return segments, nil
+	}
+
+	newest := make(map[string]bool, n)
+	for _, segment := range edgeFirst(media, len(media))[:n] {
+		newest[segment.key()] = true
+	}
+	for _, segment := range segments {
+		if segment.IsKey || segment.IsInit || newest[segment.key()] {
+			kept = append(kept, segment)
+		} else {
+			deferred = append(deferred, segment)
+		}
+	}
+	return kept, deferred
+}
+
+// edgeFirst reorders media segments so that the newest n of each playlist (the
+// live edge) come first, newest first and interleaved across playlists, followed
+// by the remaining segments in playlist order. Workers take jobs in this order.
+func edgeFirst(segments []Segment, n int) []Segment {
+	var playlists []string
+	byPlaylist := make(map[string][]Segment)
+	for _, segment := range segments {
+		if _, ok := byPlaylist[segment.Playlist]; !ok {
+			playlists = append(playlists, segment.Playlist)
+		}
+		byPlaylist[segment.Playlist] = append(byPlaylist[segment.Playlist], segment)
+	}
+
+	ordered := make([]Segment, 0, len(segments))
+	for i := 1; i <= n; i++ {
+		for _, playlist := range playlists {
+			if group := byPlaylist[playlist]; i <= len(group) {
+				ordered = append(ordered, group[len(group)-i])
+			}
+		}
+	}
+	for _, playlist := range playlists {
+		group := byPlaylist[playlist]
+		ordered = append(ordered, group[:max(len(group)-n, 0)]...)
+	}
+
+	return ordered
+}
+
+// warmSegments warms multiple segments in parallel
+func (h *HLSWarmer) warmSegments(ctx context.Context, stream Stream, segments []Segment) []CacheStatus {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	// Every segment is warmed once per edge, or once through DNS without edges
+	edges := h.edgeIPs
+	if len(edges) == 0 {
+		edges = []string{""}
+	}
+	jobs := make(chan warmJob, len(segments)*len(edges))
+	results := make(chan CacheStatus, len(segments)*len(edges))
+
+	// Start worker goroutines
+	var wg sync.WaitGroup
+	workers := h.streamWorkers(stream.URL)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go h.worker(ctx, stream, jobs, results, &wg)
+	}
+
+	// Send jobs, spaced out over the interval when pacing
+	gap := h.paceGap(stream, len(segments)*len(edges))
+	sent := 0
+dispatch:
+	for _, segment := range segments {
+		for _, edge := range edges {
+			if sent > 0 && gap > 0 && !sleepContext(ctx, gap) {
+				break dispatch
+			}
+			job := warmJob{segment: segment, edge: edge, mirror: h.nextMirror(stream)}
+			job.segment.URL = withMirrorHost(segment.URL, job.mirror)
+			jobs <- job
+			sent++
+		}
+	}
+	close(jobs)
+
+	// Collect results
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var allResults []CacheStatus
+	for result := range results {
+		allResults = append(allResults, result)
+	}
+
+	return allResults
+}
+
+// paceGap returns the delay between dispatching a daemon cycle's jobs under
+// -pace, so that they fill the stream's polling interval, or 0 when not pacing
+func (h *HLSWarmer) paceGap(stream Stream, jobs int) time.Duration {
+	interval := h.streamInterval(stream)
+	if !h.pace || !h.daemonMode || interval <= 0 || jobs <= 1 {
+		return 0
+	}
+	return interval / time.Duration(jobs)
+}
+
+// warmJob is a segment to warm through a specific edge ("" for the DNS route),
+// with its URL already pointing at the mirror it was assigned to, if any
+type warmJob struct {
+	segment Segment
+	edge    string
+	mirror  string
+}
+
+// worker processes segment warming jobs until they run out or ctx is cancelled
+func (h *HLSWarmer) worker(ctx context.Context, stream Stream, jobs <-chan warmJob, results chan<- CacheStatus, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		result := h.warmSegment(withMirror(withEdge(ctx, job.edge), job.mirror), stream, job.segment)
+		results <- result
+	}
+}
+
+// warmSegment warms a single segment, retrying transient failures and, when
+// configured, re-requesting misses until they are cached
+func (h *HLSWarmer) warmSegment(ctx context.Context, stream Stream, segment Segment) CacheStatus {
+	startTime := time.Now()
+	segmentURL := segment.URL
+
+	log := h.log
+	edge := edgeFromContext(ctx)
+	if edge != "" {
+		log = log.With("edge", edge)
+	}
+
+	if !h.quiet {
+		if segment.ByteRange != nil {
+			log.Info("Warming", Icon("🔄"), "segment", segmentURL, "range", segment.ByteRange.header())
+		} else {
+			log.Info("Warming", Icon("🔄"), "segment", segmentURL)
+		}
+	}
+
+	status := h.fetchWithRetries(ctx, stream, segment)
+
+	// Re-request misses until the edge reports a hit, since cache fills may be asynchronous
+	for i := 0; i < h.warmUntilHit && status.Error == nil && !status.Hit && status.StatusCode < 400; i++ {
+		log.Debug("MISS, re-requesting", "segment", segmentURL, "delay", h.warmUntilHitDelay, "attempt", i+1, "max", h.warmUntilHit)
+		if !sleepContext(ctx, h.warmUntilHitDelay) {
+			break
+		}
+
+		attempts := status.Attempts
+		status = h.fetchWithRetries(ctx, stream, segment)
+		status.Attempts += attempts
+	}
+	status.Time = startTime
+	status.Duration = time.Since(startTime)
+
+	if h.onSegment != nil {
+		h.onSegment(status)
+	}
+
+	if status.Error != nil {
+		return status
+	}
+
+	// Show cache status
+	if !h.quiet {
+		if status.Hit {
+			log.Info("HIT", Icon("✅"), "segment", segmentURL, "status", status.StatusCode, "duration", status.Duration)
+		} else {
+			log.Info("MISS", Icon("⚠️"), "segment", segmentURL, "status", status.StatusCode, "duration", status.Duration)
+		}
+	}
+
+	h.mu.Lock()
+	h.cacheStats[segment.key()+"@"+edge] = status
+	h.mu.Unlock()
+
+	return status
+}
+
+// fetchWithRetries fetches a segment, retrying transient failures with exponential backoff
+func (h *HLSWarmer) fetchWithRetries(ctx context.Context, stream Stream, segment Segment) CacheStatus {
+	var status CacheStatus
+	for attempt := 1; ; attempt++ {
+		status = h.fetchSegment(ctx, stream, segment)
+		status.Attempts = attempt
+
+		if attempt > h.maxRetries || !isRetryable(status) {
+			return status
+		}
+
+		// Honor the server's Retry-After over our own backoff when rate limited
+		delay := h.retryBaseDelay << (attempt - 1)
+		if status.RetryAfter > 0 {
+			delay = h.capRetryAfter(status.RetryAfter)
+		}
+		h.log.Debug("Retrying", "segment", segment.URL, "delay", delay, "attempt", attempt+1, "max", h.maxRetries+1)
+		if !sleepContext(ctx, delay) {
+			return status
+		}
+	}
+}
+
+// fetchSegment performs a single request for a segment and reports its cache status
+func (h *HLSWarmer) fetchSegment(ctx context.Context, stream Stream, segment Segment) CacheStatus {
+	startTime := time.Now()
+	status := CacheStatus{
+		URL:           segment.URL,
+		ByteRange:     segment.ByteRange,
+		IsKey:         segment.IsKey,
+		IsInit:        segment.IsInit,
+		Discontinuity: segment.Discontinuity,
+		MediaDuration: segment.Duration,
+		Edge:          edgeFromContext(ctx),
+		Mirror:        mirrorFromContext(ctx),
+	}
+
+	// Wait for the global rate limiter before issuing the request
+	if h.limiter != nil {
+		if err := h.limiter.Wait(ctx); err != nil {
+			status.Error = err
+			status.Duration = time.Since(startTime)
+			return status
+		}
+	}
+
+	// Wait for a slot on the segment's host so small origins are not overwhelmed
+	release, err := h.acquireHostSlot(ctx, segment.URL)
+	if err != nil {
+		status.Error = err
+		status.Duration = time.Since(startTime)
+		return status
+	}
+	defer release()
+
+	// The deadline covers the whole request, including reading the body
+	ctx, cancel := context.WithTimeout(ctx, h.requestTimeout)
+	defer cancel()
+
+	// Pick the User-Agent up front so that it is known even when the request fails
+	status.UserAgent = h.nextUserAgent()
+	ctx = withUserAgent(ctx, status.UserAgent)
+
+	mode := h.requestModeFor(ctx)
+	method := mode.method
+	if template := stream.SegmentRequest; template != nil {
+		method = template.method()
+	}
+	ctx, span := h.spans.Start(ctx, "warm segment", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("url.full", segment.URL),
+		attribute.String("http.request.method", method),
+	))
+	defer func() { endSegmentSpan(span, status) }()
+
+	ctx, timing := withTracer(ctx)
+	byteRange := prefetchRange(segment, mode)
+	resp, err := h.makeRequest(ctx, stream, method, segment.URL, byteRange)
+
+	// Fetch the whole segment when the origin cannot satisfy the prefetch range
+	if err == nil && byteRange != segment.ByteRange && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		resp.Body.Close()
+		h.log.Debug("Prefetch range not satisfiable, fetching whole segment", "segment", segment.URL)
+		byteRange = segment.ByteRange
+		resp, err = h.makeRequest(ctx, stream, method, segment.URL, byteRange)
+	}
+
+	// Fall back to GET for origins that do not allow HEAD
+	if err == nil && method == http.MethodHead && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		h.log.Debug("HEAD not allowed, falling back to GET", "segment", segment.URL)
+		method = http.MethodGet
+		resp, err = h.makeRequest(ctx, stream, method, segment.URL, segment.ByteRange)
+	}
+	status.Method = method
+
+	if err != nil {
+		// Clean error message to prevent terminal corruption
+		status.Error = fmt.Errorf("%s", cleanString(err.Error()))
+		if errors.Is(err, errTooManyRedirects) {
+			status.Error = err
+		}
+		status.Duration = time.Since(startTime)
+		return status
+	}
+	defer resp.Body.Close()
+
+	// Cache status and headers below describe the final hop of any redirects
+	status.Redirects = redirectChain(resp)
+	if len(status.Redirects) > 0 {
+		h.log.Debug("Followed redirects", "segment", segment.URL, "chain", status.Redirects)
+	}
+
+	// An origin that ignores the prefetch range answers 200 with the whole body
+	status.Partial = byteRange != segment.ByteRange && resp.StatusCode == http.StatusPartialContent
+
+	// Read response (for caching); HEAD responses carry no body
+	status.Proto = resp.Proto
+	status.ContentType = resp.Header.Get("Content-Type")
+	status.Size = resp.ContentLength
+	status.WireBytes = resp.ContentLength
+	if method != http.MethodHead {
+		wire, decoded, err := discardBody(resp, h.maxBodyBytes)
+		status.WireBytes, status.Size = wire, decoded
+		if err != nil {
+			// Clean error message to prevent terminal corruption
+			status.Error = err
+			if !errors.Is(err, errBodyTooLarge) {
+				status.Error = fmt.Errorf("%s", cleanString(err.Error()))
+			}
+			status.StatusCode = resp.StatusCode
+			status.Timing = timing.finish()
+			status.Duration = time.Since(startTime)
+			return status
+		}
+	}
+	status.Timing = timing.finish()
+	h.log.Debug("Request timing", "segment", segment.URL, "dns", status.Timing.DNS, "connect", status.Timing.Connect,
+		"tls", status.Timing.TLS, "ttfb", status.Timing.TTFB, "transfer", status.Timing.Transfer, "reused", status.Timing.Reused)
+
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		status.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	// Check cache status
+	status.Hit = h.detectCacheHit(resp)
+	status.StatusCode = resp.StatusCode
+	status.Headers = headers
+	status.Suspicious = h.suspiciousReason(segment, status)
+	status.Duration = time.Since(startTime)
+
+	return status
+}
+
+// segmentContentTypes are the Content-Types expected for media and init segments
+var segmentContentTypes = []string{
+	"video/mp2t",
+	"video/mp4",
+	"video/iso.segment",
+	"audio/mp4",
+	"audio/aac",
+	"application/mp4",
+	"application/octet-stream",
+	"text/vtt",
+}
+
+// suspiciousReason reports why a successful segment response looks like something
+// other than media, such as a small HTML error page served with status 200. Keys
+// are never flagged since they are tiny and served with arbitrary types.
+func (h *HLSWarmer) suspiciousReason(segment Segment, status CacheStatus) string {
+	if segment.IsKey || status.StatusCode < 200 || status.StatusCode >= 300 {
+		return ""
+	}
+
+	if h.checkContentType {
+		mediaType, _, err := mime.ParseMediaType(status.ContentType)
+		if err != nil || !slices.Contains(segmentContentTypes, strings.ToLower(mediaType)) {
+			return fmt.Sprintf("unexpected Content-Type %q", status.ContentType)
+		}
+	}
+
+	// Init segments are legitimately small, so only media segments are size checked;
+	// a prefetch is as small as it was asked to be
+	if h.minSegmentBytes > 0 && !segment.IsInit && !status.Partial && status.Size >= 0 && status.Size < h.minSegmentBytes {
+		return fmt.Sprintf("only %d bytes", status.Size)
+	}
+	return ""
+}
+
+// prefetchRange returns the range to request for a segment: its own byte range,
+// cut down to the mode's first prefetch bytes when prefetching. HEAD requests carry no
+// body, so they are never cut down.
+func prefetchRange(segment Segment, mode requestMode) *ByteRange {
+	if mode.prefetchBytes <= 0 || mode.method == http.MethodHead {
+		return segment.ByteRange
+	}
+	if segment.ByteRange == nil {
+		return &ByteRange{Length: mode.prefetchBytes}
+	}
+	if segment.ByteRange.Length <= mode.prefetchBytes {
+		return segment.ByteRange
+	}
+	return &ByteRange{Offset: segment.ByteRange.Offset, Length: mode.prefetchBytes}
+}
+
+// acquireHostSlot blocks until fewer than -per-host-workers requests are in flight
+// to the host of rawURL, returning a function that frees the slot. Without a
+// per-host limit it returns immediately.
+func (h *HLSWarmer) acquireHostSlot(ctx context.Context, rawURL string) (func(), error) {
+	if h.perHostWorkers <= 0 {
+		return func() {}, nil
+	}
+
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil {
+		host = parsed.Host
+	}
+
+	h.hostSlotsMu.Lock()
+	slots, ok := h.hostSlots[host]
+	if !ok {
+		slots = make(chan struct{}, h.perHostWorkers)
+		h.hostSlots[host] = slots
+	}
+	h.hostSlotsMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// isRetryable reports whether a failed attempt is worth retrying: network errors,
+// server errors and rate limiting are transient, other client errors are not
+func isRetryable(status CacheStatus) bool {
+	if status.Error != nil {
+		// An oversized body will be just as large on the next attempt, and a
+		// redirect chain just as long
+		return !errors.Is(status.Error, errBodyTooLarge) && !errors.Is(status.Error, errTooManyRedirects)
+	}
+	return status.StatusCode >= 500 || status.StatusCode == http.StatusTooManyRequests
+}
+
+// PrintResults prints the warming results
+func (h *HLSWarmer) PrintResults(result *WarmResult) {
+	fmt.Fprintf(h.out, "\n📊 RESULTS\n")
+	fmt.Fprintf(h.out, "==========================================\n")
+	fmt.Fprintf(h.out, "M3U8 URL: %s\n", result.M3U8URL)
+	fmt.Fprintf(h.out, "Total Files: %d\n", result.TotalFiles)
+	fmt.Fprintf(h.out, "Cache Hit: %d\n", result.CachedFiles)
+	fmt.Fprintf(h.out, "Cache Miss: %d\n", result.TotalFiles-result.CachedFiles)
+	fmt.Fprintf(h.out, "Error Count: %d\n", len(result.Errors))
+	if result.Duplicates > 0 {
+		fmt.Fprintf(h.out, "Duplicates Skipped: %d\n", result.Duplicates)
+	}
+	if result.SuspiciousFiles > 0 {
+		fmt.Fprintf(h.out, "Suspicious: %d\n", result.SuspiciousFiles)
+	}
+	if result.Disallowed > 0 {
+		fmt.Fprintf(h.out, "Disallowed Hosts Skipped: %d\n", result.Disallowed)
+	}
+	if result.Deferred > 0 {
+		fmt.Fprintf(h.out, "Deferred by Segment Limit: %d\n", result.Deferred)
+	}
+	if result.DeadlineExceeded {
+		fmt.Fprintf(h.out, "⏰ Deadline Exceeded: %d segments not warmed\n", result.Unwarmed)
+	}
+	fmt.Fprintf(h.out, "Bytes Warmed: %s (%s transferred)\n", formatBytes(result.TotalBytes), formatBytes(result.WireBytes))
+	fmt.Fprintf(h.out, "Total Duration: %v\n", result.Duration)
+	if result.TotalFiles > 0 {
+		fmt.Fprintf(h.out, "Latency: p50 %v, p95 %v, p99 %v\n",
+			result.P50.Round(time.Microsecond), result.P95.Round(time.Microsecond), result.P99.Round(time.Microsecond))
+	}
+	if result.ReusedConns+result.NewConns > 0 {
+		fmt.Fprintf(h.out, "Connection Reuse: %.2f%% (%d reused, %d new)\n", result.connReuseRatio()*100, result.ReusedConns, result.NewConns)
+	}
+	if result.TotalDuration > 0 {
+		fmt.Fprintf(h.out, "Content Warmed: %v in %v wall-clock (%.1f× real-time)\n",
+			result.TotalDuration, result.Duration.Round(time.Millisecond), result.realTimeRatio())
+	}
+	if result.TotalFiles > 0 {
+		fmt.Fprintf(h.out, "Cache Ratio: %.2f%%\n", float64(result.CachedFiles)/float64(result.TotalFiles)*100)
+	}
+	if result.SuccessCriteria != SuccessCacheHit {
+		fmt.Fprintf(h.out, "Success Ratio (%s): %.2f%% (%d/%d)\n", result.SuccessCriteria, result.SuccessRatio()*100, result.Succeeded, result.TotalFiles)
+	}
+
+	noResponse := 0
+	for _, r := range result.Details {
+		if r.StatusCode == 0 && r.Error != nil {
+			noResponse++
+		}
+	}
+	if len(result.StatusCodes) > 0 || noResponse > 0 {
+		fmt.Fprintf(h.out, "\n🔢 STATUS CODES:\n")
+		for _, code := range slices.Sorted(maps.Keys(result.StatusCodes)) {
+			fmt.Fprintf(h.out, "%d %s: %d\n", code, http.StatusText(code), result.StatusCodes[code])
+		}
+		if noResponse > 0 {
+			fmt.Fprintf(h.out, "No response: %d\n", noResponse)
+		}
+	}
+
+	if len(result.Variants) > 0 {
+		fmt.Fprintf(h.out, "\n🎞️ VARIANTS:\n")
+		for i, variant := range result.Variants {
+			kind := ""
+			if variant.IFrame {
+				kind = "I-frame, "
+			} else if variant.Media != "" {
+				kind = strings.ToLower(variant.Media) + ", "
+			}
+			fmt.Fprintf(h.out, "%d. %s (%s%d segments)\n", i+1, variant.URL, kind, variant.Segments)
+		}
+	}
+
+	if len(h.edgeIPs) > 0 {
+		fmt.Fprintf(h.out, "\n🌍 EDGES:\n")
+		for i, edge := range groupResults(result.Details, func(r CacheStatus) string { return r.Edge }) {
+			fmt.Fprintf(h.out, "%d. %s: %d/%d hits, %d errors\n", i+1, edge.name, edge.hits, edge.total, edge.errors)
+		}
+	}
+
+	if slices.ContainsFunc(result.Details, func(r CacheStatus) bool { return r.Mirror != "" }) {
+		fmt.Fprintf(h.out, "\n🪞 MIRRORS:\n")
+		for i, mirror := range groupResults(result.Details, func(r CacheStatus) string { return r.Mirror }) {
+			fmt.Fprintf(h.out, "%d. %s: %d/%d hits, %d errors\n", i+1, mirror.name, mirror.hits, mirror.total, mirror.errors)
+		}
+	}
+
+	if len(h.userAgents) > 1 {
+		fmt.Fprintf(h.out, "\n📱 USER AGENTS:\n")
+		for i, group := range groupResults(result.Details, func(r CacheStatus) string { return r.UserAgent }) {
+			fmt.Fprintf(h.out, "%d. %s: %d/%d hits, %d errors\n", i+1, group.name, group.hits, group.total, group.errors)
+		}
+	}
+
+	// Group by discontinuity only when there is more than one, e.g. ad breaks
+	discontinuities := groupResults(result.Details, func(r CacheStatus) string {
+		return strconv.FormatInt(r.Discontinuity, 10)
+	})
+	if len(discontinuities) > 1 {
+		fmt.Fprintf(h.out, "\n✂️ DISCONTINUITIES:\n")
+		for _, group := range discontinuities {
+			fmt.Fprintf(h.out, "Discontinuity %s: %d/%d hits, %d errors\n", group.name, group.hits, group.total, group.errors)
+		}
+	}
+
+	if v := result.Verification; v != nil {
+		fmt.Fprintf(h.out, "\n🔁 VERIFICATION (after %v):\n", v.Delay)
+		fmt.Fprintf(h.out, "Still HIT: %d/%d\n", v.Hits, len(v.Details))
+		fmt.Fprintf(h.out, "Evicted (HIT → MISS): %d\n", v.Evicted)
+		fmt.Fprintf(h.out, "Never Cached (MISS → MISS): %d\n", v.NeverCached)
+		fmt.Fprintf(h.out, "Errors: %d\n", v.Errors)
+		for _, d := range v.Details {
+			if d.After.Hit && d.After.Error == nil {
+				continue
+			}
+			url := d.Before.URL
+			if d.Before.Edge != "" {
+				url += " @ " + d.Before.Edge
+			}
+			switch {
+			case d.After.Error != nil:
+				fmt.Fprintf(h.out, "⚠️ ERROR - %s: %v\n", url, d.After.Error)
+			case d.Before.Hit:
+				fmt.Fprintf(h.out, "HIT → MISS (%d) - %s\n", d.After.StatusCode, url)
+			default:
+				fmt.Fprintf(h.out, "MISS → MISS (%d) - %s\n", d.After.StatusCode, url)
+			}
+		}
+	}
+
+	if p := result.Purge; p != nil {
+		fmt.Fprintf(h.out, "\n🧹 PURGE:\n")
+		fmt.Fprintf(h.out, "Purged: %d/%d\n", p.Purged, len(p.Details))
+		fmt.Fprintf(h.out, "Not Cached (404): %d\n", p.NotCached)
+		fmt.Fprintf(h.out, "Failed: %d\n", p.Failed)
+		for _, d := range p.Details {
+			url := d.URL
+			if d.Edge != "" {
+				url += " @ " + d.Edge
+			}
+			switch {
+			case d.Error != nil:
+				fmt.Fprintf(h.out, "⚠️ ERROR - %s: %v\n", url, d.Error)
+			case d.StatusCode != http.StatusNotFound && (d.StatusCode < 200 || d.StatusCode >= 300):
+				fmt.Fprintf(h.out, "⚠️ FAILED (%d) - %s\n", d.StatusCode, url)
+			}
+		}
+	}
+
+	if len(result.ParseWarnings) > 0 {
+		fmt.Fprintf(h.out, "\n🩺 PLAYLIST WARNINGS:\n")
+		for _, w := range result.ParseWarnings {
+			fmt.Fprintf(h.out, "⚠️ %s\n", w)
+		}
+	}
+
+	if c := result.Continuity; c != nil {
+		fmt.Fprintf(h.out, "\n🧵 CONTINUITY:\n")
+		fmt.Fprintf(h.out, "Sequence Gaps: %d\n", len(c.Gaps))
+		for _, gap := range c.Gaps {
+			fmt.Fprintf(h.out, "🕳️ #%d-#%d (%d missing) - %s\n", gap.From, gap.To, gap.To-gap.From+1, gap.Playlist)
+		}
+		fmt.Fprintf(h.out, "Missing Segments (404): %d\n", len(c.Missing))
+		for _, url := range c.Missing {
+			fmt.Fprintf(h.out, "🕳️ %s\n", url)
+		}
+	}
+
+	if len(result.MethodComparison) > 0 {
+		fmt.Fprintf(h.out, "\n🔬 METHOD COMPARISON:\n")
+		for _, c := range result.MethodComparison {
+			parts := make([]string, len(c.Results))
+			for i, r := range c.Results {
+				parts[i] = r.Label + ": " + describeMethodResult(r)
+			}
+			fmt.Fprintf(h.out, "%s - %s\n", strings.Join(parts, " | "), c.URL)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Fprintf(h.out, "\n⚠️ ERRORS:\n")
+		for i, err := range result.Errors {
+			fmt.Fprintf(h.out, "%d. %v\n", i+1, err)
+		}
+	}
+
+	fmt.Fprintf(h.out, "\n🔍 DETAILS:\n")
+	for i, detail := range result.Details {
+		status := "⚠️ MISS"
+		if detail.Hit {
+			status = "✅ HIT"
+		}
+		if detail.IsKey {
+			status += " 🔑"
+		}
+		if detail.IsInit {
+			status += " 🧩 INIT"
+		}
+		if detail.Partial {
+			status += " 🔹 PARTIAL"
+		}
+		if detail.Suspicious != "" {
+			status += " 🚩 SUSPICIOUS (" + detail.Suspicious + ")"
+		}
+		url := detail.URL
+		if detail.ByteRange != nil {
+			url += " [" + detail.ByteRange.header() + "]"
+		}
+		if detail.Edge != "" {
+			url += " @ " + detail.Edge
+		}
+
+		timing := detail.Duration.String()
+		if detail.Attempts > 1 {
+			timing = fmt.Sprintf("%v, %d attempts", detail.Duration, detail.Attempts)
+		}
+		if detail.Proto != "" {
+			timing += ", " + detail.Proto
+		}
+
+		if detail.Error != nil {
+			fmt.Fprintf(h.out, "%d. ⚠️ ERROR - %s: %v [%s]\n", i+1, url, detail.Error, timing)
+		} else {
+			fmt.Fprintf(h.out, "%d. %s (%d) - %s [%s]\n", i+1, status, detail.StatusCode, url, timing)
+		}
+		if len(detail.Redirects) > 0 {
+			fmt.Fprintf(h.out, "   ↪ %s\n", strings.Join(detail.Redirects[1:], " → "))
+		}
+	}
+}
+
+// resultGroup counts the results sharing a grouping key, such as an edge
+type resultGroup struct {
+	name   string
+	total  int
+	hits   int
+	errors int
+}
+
+// groupResults groups results by the key returned by groupBy, in order of first appearance
+func groupResults(results []CacheStatus, groupBy func(CacheStatus) string) []resultGroup {
+	var summaries []resultGroup
+	index := make(map[string]int)
+	for _, r := range results {
+		name := groupBy(r)
+		i, ok := index[name]
+		if !ok {
+			i = len(summaries)
+			index[name] = i
+			summaries = append(summaries, resultGroup{name: name})
+		}
+		summaries[i].total++
+		if r.Error != nil {
+			summaries[i].errors++
+		} else if r.Hit {
+			summaries[i].hits++
+		}
+	}
+	return summaries
+}
+
+// beginStreamProcessing marks a stream as being processed if it is not already.
+// Returns true when processing should continue, false when another worker already handles it.
+func (h *HLSWarmer) beginStreamProcessing(stream string) bool {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+
+	if h.streamActive[stream] {
+		return false
+	}
+
+	h.streamActive[stream] = true
+	return true
+}
+
+// endStreamProcessing clears the processing flag for the given stream.
+func (h *HLSWarmer) endStreamProcessing(stream string) {
+	h.streamMu.Lock()
+	delete(h.streamActive, stream)
+	h.streamMu.Unlock()
+}
+
+// pauseStream suspends warm cycles for the given stream until the delay has passed.
+func (h *HLSWarmer) pauseStream(stream string, delay time.Duration) {
+	h.streamMu.Lock()
+	h.streamPaused[stream] = time.Now().Add(delay)
+	h.streamMu.Unlock()
+}
+
+// streamPausedFor returns how long the given stream remains paused, or 0 if it is not.
+func (h *HLSWarmer) streamPausedFor(stream string) time.Duration {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+
+	until, ok := h.streamPaused[stream]
+	if !ok {
+		return 0
+	}
+	if remaining := time.Until(until); remaining > 0 {
+		return remaining
+	}
+	delete(h.streamPaused, stream)
+	return 0
+}
+
+// markStreamEnded records that the given stream's playlist is complete.
+func (h *HLSWarmer) markStreamEnded(stream string) {
+	h.streamMu.Lock()
+	h.streamEnded[stream] = true
+	h.streamMu.Unlock()
+}
+
+// streamHasEnded reports whether the given stream's playlist is complete.
+func (h *HLSWarmer) streamHasEnded(stream string) bool {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	return h.streamEnded[stream]
+}
+
+// setStreamTargetDuration records the latest EXT-X-TARGETDURATION seen for the given stream.
+func (h *HLSWarmer) setStreamTargetDuration(stream string, target time.Duration) {
+	h.streamMu.Lock()
+	h.streamTarget[stream] = target
+	h.streamMu.Unlock()
+}
+
+// streamTargetDuration returns the latest EXT-X-TARGETDURATION for the given stream, or 0 if unknown.
+func (h *HLSWarmer) streamTargetDuration(stream string) time.Duration {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	return h.streamTarget[stream]
+}
+
+// setStreamResult records the latest warm result of a daemon stream
+func (h *HLSWarmer) setStreamResult(stream string, result *WarmResult) {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	if _, running := h.streamRuns[stream]; running {
+		h.streamResults[stream] = result
+	}
+}
@@ -0,0 +1,168 @@
+package hlswarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultWebhookInterval is the shortest time between two webhook
+	// notifications for the same stream
+	DefaultWebhookInterval = 5 * time.Minute
+	// webhookAttempts is how many times a notification is sent before giving up
+	webhookAttempts = 3
+	// webhookRetryDelay is the wait before the first resend, doubled on each attempt
+	webhookRetryDelay = time.Second
+)
+
+// Webhook notification reasons
+const (
+	webhookCycleFailed = "cycle_failed"
+	webhookLowHitRatio = "low_hit_ratio"
+)
+
+// webhookPayload is the JSON body POSTed to -webhook-url. Text is a one-line
+// summary, so Slack-compatible incoming webhooks can display it as is.
+type webhookPayload struct {
+	Text      string    `json:"text"`
+	Stream    string    `json:"stream"`
+	Reason    string    `json:"reason"`
+	Error     string    `json:"error,omitempty"`
+	Segments  int       `json:"segments"`
+	Errors    int       `json:"errors"`
+	HitRatio  float64   `json:"hit_ratio"`
+	Timestamp time.Time `json:"timestamp"`
+	// Suppressed counts the notifications for the stream dropped by the rate limit
+	// since the previous one was sent
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// webhookState rate-limits the notifications of a daemon stream
+type webhookState struct {
+	sent       time.Time
+	suppressed int
+}
+
+// notifyWebhook POSTs a notification to -webhook-url when a daemon cycle failed,
+// as the circuit breaker counts failures, or its hit ratio is below
+// -webhook-min-hit-ratio. A stream is notified at most once per -webhook-interval
+// so a flapping stream does not flood the receiver; the notification is sent in
+// the background so a slow receiver does not delay the stream's cycles.
+func (h *HLSWarmer) notifyWebhook(ctx context.Context, stream Stream, result *WarmResult, err error) {
+	if h.webhookURL == "" {
+		return
+	}
+	failed, counted := h.cycleFailed(ctx, result, err)
+	if !counted {
+		return
+	}
+
+	payload := webhookPayload{Stream: stream.URL, Timestamp: time.Now().UTC()}
+	if result != nil {
+		payload.Segments, payload.Errors = result.TotalFiles, len(result.Errors)
+		if result.TotalFiles > 0 {
+			payload.HitRatio = float64(result.CachedFiles) / float64(result.TotalFiles)
+		}
+	}
+	switch {
+	case failed:
+		payload.Reason = webhookCycleFailed
+		if err != nil {
+			payload.Error = err.Error()
+			payload.Text = fmt.Sprintf("🔥 Warm cycle failed for %s: %v", stream.URL, err)
+		} else {
+			payload.Text = fmt.Sprintf("🔥 Warm cycle failed for %s: %d of %d segments errored", stream.URL, payload.Errors, payload.Segments)
+		}
+	case payload.Segments > 0 && payload.HitRatio < h.webhookMinHitRatio:
+		payload.Reason = webhookLowHitRatio
+		payload.Text = fmt.Sprintf("🔥 Hit ratio of %s dropped to %.0f%% (%d of %d segments cached)",
+			stream.URL, payload.HitRatio*100, result.CachedFiles, payload.Segments)
+	default:
+		return
+	}
+
+	h.streamMu.Lock()
+	state, ok := h.streamWebhooks[stream.URL]
+	if !ok {
+		state = &webhookState{}
+		h.streamWebhooks[stream.URL] = state
+	}
+	if !state.sent.IsZero() && time.Since(state.sent) < h.webhookInterval {
+		state.suppressed++
+		h.streamMu.Unlock()
+		h.log.Debug("Webhook notification rate-limited", Icon("📣"), "stream", stream.URL, "reason", payload.Reason)
+		return
+	}
+	state.sent = time.Now()
+	payload.Suppressed, state.suppressed = state.suppressed, 0
+	h.streamMu.Unlock()
+
+	h.webhookWG.Go(func() {
+		if err := h.sendWebhook(payload); err != nil {
+			h.log.Warn("Webhook notification failed", Icon("📣"), "stream", stream.URL, "reason", payload.Reason, "error", err)
+			return
+		}
+		h.log.Info("Webhook notification sent", Icon("📣"), "stream", stream.URL, "reason", payload.Reason)
+	})
+}
+
+// sendWebhook POSTs a payload to -webhook-url, resending it after network errors,
+// 429s and 5xx responses with a doubling delay. It is not tied to the daemon's
+// context, so a failure seen while shutting down is still delivered.
+func (h *HLSWarmer) sendWebhook(payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = h.postWebhook(body)
+		var status *webhookStatusError
+		retryable := err != nil && (!errors.As(err, &status) || status.code == http.StatusTooManyRequests || status.code >= 500)
+		if !retryable || attempt == webhookAttempts {
+			return err
+		}
+		h.log.Debug("Retrying webhook notification", Icon("📣"), "attempt", attempt, "delay", delay, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// webhookStatusError is a webhook response with a non-2xx status
+type webhookStatusError struct {
+	code int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook returned %d %s", e.code, http.StatusText(e.code))
+}
+
+// postWebhook sends one webhook request
+func (h *HLSWarmer) postWebhook(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s", cleanString(err.Error()))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &webhookStatusError{code: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package hlswarm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseWarning is a problem found in a playlist that does not stop it from being
+// parsed, such as an #EXTINF without a segment URI or a missing required tag
+type ParseWarning struct {
+	Playlist string `json:"playlist"`
+	// Line is the 1-based line number, 0 for problems with the playlist as a whole
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats a warning as "playlist:line: message"
+func (w ParseWarning) String() string {
+	if w.Line == 0 {
+		return w.Playlist + ": " + w.Message
+	}
+	return w.Playlist + ":" + strconv.Itoa(w.Line) + ": " + w.Message
+}
+
+// checkWarnings handles the warnings found in a playlist: with -strict the first
+// one fails the parse, otherwise each is logged the first time it is seen, so a
+// daemon polling a broken playlist does not repeat them every cycle
+func (h *HLSWarmer) checkWarnings(warnings []ParseWarning) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	if h.strict {
+		if len(warnings) > 1 {
+			return fmt.Errorf("malformed playlist: %s (and %d more)", warnings[0], len(warnings)-1)
+		}
+		return fmt.Errorf("malformed playlist: %s", warnings[0])
+	}
+
+	h.warnedMu.Lock()
+	defer h.warnedMu.Unlock()
+	for _, w := range warnings {
+		key := w.Playlist + "\n" + w.Message
+		if h.warned[key] {
+			continue
+		}
+		h.warned[key] = true
+		h.log.Warn("Malformed playlist", Icon("🩺"), "playlist", w.Playlist, "line", w.Line, "problem", w.Message)
+	}
+	return nil
+}
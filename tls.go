@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// loadTLSConfig builds the client TLS configuration from the TLS flags. It returns
+// nil when none are set, keeping Go's default verification against system roots.
+func loadTLSConfig(insecureSkipVerify bool, caFile, certFile, keyFile string) (*tls.Config, error) {
+	if !insecureSkipVerify && caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("-client-cert and -client-key must be given together")
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
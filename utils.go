@@ -1,64 +1,84 @@
 package main
 
 import (
-	"crypto/rand"
-	"fmt"
+	"bufio"
+	"io"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
-// generateUUID generates a random UUID v4
-func generateUUID() string {
-	b := make([]byte, 16)
-	rand.Read(b)
+// splitStreamInterval splits a "url@interval" stream argument (e.g. "https://example.com/live.m3u8@10s")
+// into its URL and check interval. Arguments without a valid duration suffix are returned unchanged,
+// so URLs containing "@" in their userinfo still work.
+func splitStreamInterval(arg string) (string, time.Duration) {
+	i := strings.LastIndex(arg, "@")
+	if i < 0 {
+		return arg, 0
+	}
 
-	// Set version (4) and variant bits
-	b[6] = (b[6] & 0x0f) | 0x40
-	b[8] = (b[8] & 0x3f) | 0x80
+	interval, err := time.ParseDuration(arg[i+1:])
+	if err != nil || interval <= 0 {
+		return arg, 0
+	}
 
-	return fmt.Sprintf("%08X-%04X-%04X-%04X-%012X",
-		b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	return arg[:i], interval
 }
 
-// extractBaseURL extracts the base URL (scheme + host) from a given URL
-func extractBaseURL(urlStr string) string {
-	parsedURL, err := url.Parse(urlStr)
+// localPlaylistURL turns a playlist argument naming a file on disk into a
+// file:// URL; URLs and anything that is not an existing file are returned as-is
+func localPlaylistURL(arg string) string {
+	if strings.Contains(arg, "://") {
+		return arg
+	}
+	info, err := os.Stat(arg)
+	if err != nil || info.IsDir() {
+		return arg
+	}
+	abs, err := filepath.Abs(arg)
 	if err != nil {
-		return ""
+		return arg
 	}
-
-	// Return scheme + host (e.g., "https://example.com")
-	return parsedURL.Scheme + "://" + parsedURL.Host
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
 }
 
-// cleanString removes non-printable characters that might corrupt terminal output
-func cleanString(s string) string {
-	// Remove null bytes and other problematic characters
-	s = strings.ReplaceAll(s, "\x00", "")
-
-	// Replace non-printable characters except for tab, newline, and carriage return
-	return strings.Map(func(r rune) rune {
-		// Allow normal printable ASCII characters and some basic whitespace
-		if (r >= 32 && r <= 126) || r == 9 || r == 10 || r == 13 {
-			return r
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
-		// Skip/remove problematic characters completely for URLs
-		return -1
-	}, s)
+	}
+	return items
 }
 
-// resolveURL resolves a relative URL against a base URL
-func resolveURL(baseURL *url.URL, segment string) string {
-	// If segment is already a full URL, use it directly
-	if strings.HasPrefix(segment, "http://") || strings.HasPrefix(segment, "https://") {
-		return segment
+// readListFile reads a list such as URLs or User-Agents from a file, or from
+// stdin when path is "-"
+func readListFile(path string) ([]string, error) {
+	if path == "-" {
+		return readList(os.Stdin)
 	}
-
-	// Resolve relative URL
-	segmentURL, err := url.Parse(segment)
+	file, err := os.Open(path)
 	if err != nil {
-		return segment
+		return nil, err
 	}
+	defer file.Close()
+	return readList(file)
+}
 
-	return baseURL.ResolveReference(segmentURL).String()
+// readList reads one entry per line, skipping blank lines and "#" comments
+func readList(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
 }